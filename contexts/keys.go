@@ -3,13 +3,18 @@ package contexts
 type contextKey string
 
 const (
-	KeyTenantID  contextKey = "tenantID"
-	KeyUserID    contextKey = "userID"
-	KeyUsername  contextKey = "username"
-	KeyUserRoles contextKey = "userRoles"
-	KeyPlayerID  contextKey = "playerID"
-	KeyRequestID contextKey = "requestID"
-	KeyOrigin    contextKey = "origin"
-	KeyUserAgent contextKey = "userAgent"
-	KeyRegion    contextKey = "region"
+	KeyTenantID    contextKey = "tenantID"
+	KeyUserID      contextKey = "userID"
+	KeyUsername    contextKey = "username"
+	KeyUserRoles   contextKey = "userRoles"
+	KeyPlayerID    contextKey = "playerID"
+	KeyRequestID   contextKey = "requestID"
+	KeyOrigin      contextKey = "origin"
+	KeyUserAgent   contextKey = "userAgent"
+	KeyRegion      contextKey = "region"
+	KeyAPIVersion  contextKey = "apiVersion"
+	KeyCallerID    contextKey = "callerID"
+	KeyCallerChain contextKey = "callerChain"
+	KeyTraceParent contextKey = "traceParent"
+	KeyTraceState  contextKey = "traceState"
 )
@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -12,13 +13,21 @@ type AppError struct {
 	Code       string
 	Message    string
 	Context    map[string]interface{}
+	// Internal holds context for logs only; it is never serialized.
+	Internal map[string]interface{}
+
+	stack []uintptr
 }
 
 func New() *AppError {
-	return &AppError{
+	e := &AppError{
 		HTTPStatus: http.StatusInternalServerError,
 		Context:    map[string]interface{}{},
 	}
+	if captureStack.Load() {
+		e.stack = callers(3)
+	}
+	return e
 }
 
 func (e *AppError) clone() *AppError {
@@ -27,6 +36,16 @@ func (e *AppError) clone() *AppError {
 	for k, v := range e.Context {
 		c.Context[k] = v
 	}
+	if e.Internal != nil {
+		c.Internal = make(map[string]interface{}, len(e.Internal))
+		for k, v := range e.Internal {
+			c.Internal[k] = v
+		}
+	}
+	if captureStack.Load() {
+		// Skip callers, clone and the With* method.
+		c.stack = callers(4)
+	}
 	return &c
 }
 
@@ -60,7 +79,25 @@ func (e *AppError) WithContext(key string, value interface{}) *AppError {
 	return c
 }
 
+// WithInternalContext adds context that is logged but never sent to clients.
+func (e *AppError) WithInternalContext(key string, value interface{}) *AppError {
+	c := e.clone()
+	if c.Internal == nil {
+		c.Internal = map[string]interface{}{}
+	}
+	c.Internal[key] = value
+	return c
+}
+
 func (e *AppError) Error() string {
+	msg := e.message()
+	if development.Load() && len(e.stack) > 0 {
+		msg += "\n" + FormatStack(e.stack)
+	}
+	return msg
+}
+
+func (e *AppError) message() string {
 	if e.Err != nil && e.Message != "" {
 		return fmt.Sprintf("%s: %v", e.Message, e.Err)
 	}
@@ -92,3 +129,40 @@ func FromError(err error) (*AppError, bool) {
 	}
 	return nil, false
 }
+
+// Is reports whether target is an *AppError with the same non-empty code, so
+// errors.Is(err, ErrSomething) matches values derived with the With* methods.
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// HasCode reports whether any AppError in err's chain carries code.
+func HasCode(err error, code string) bool {
+	return code != "" && errors.Is(err, &AppError{Code: code})
+}
+
+type publicError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Context map[string]interface{} `json:"context,omitempty"`
+}
+
+func (e *AppError) public() publicError {
+	return publicError{Code: e.Code, Message: e.Message, Context: e.Context}
+}
+
+// MarshalJSON emits {code, message, context}; the wrapped error and the
+// internal context are left out.
+func (e *AppError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.public())
+}
+
+// Public is the client-safe projection of e, matching MarshalJSON.
+func (e *AppError) Public() map[string]interface{} {
+	m := map[string]interface{}{"code": e.Code, "message": e.Message}
+	if len(e.Context) > 0 {
+		m["context"] = e.Context
+	}
+	return m
+}
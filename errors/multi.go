@@ -0,0 +1,85 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Multi aggregates the errors of a batch operation. errors.Is/As match any of
+// the contained errors and it marshals to a JSON array of error objects.
+type Multi struct {
+	Errors []*AppError
+}
+
+// Append adds err, flattening nested Multi values. Plain errors are wrapped in
+// an INTERNAL_ERROR AppError; nil is ignored.
+func (m *Multi) Append(err error) {
+	if err == nil {
+		return
+	}
+	var nested *Multi
+	if errors.As(err, &nested) {
+		m.Errors = append(m.Errors, nested.Errors...)
+		return
+	}
+	if ae, ok := FromError(err); ok {
+		m.Errors = append(m.Errors, ae)
+		return
+	}
+	m.Errors = append(m.Errors, New().
+		WithCode("INTERNAL_ERROR").
+		WithMessage("internal error").
+		WithError(err))
+}
+
+// ErrorOrNil returns m as an error, or a nil error when nothing was appended.
+func (m *Multi) ErrorOrNil() error {
+	if m == nil || len(m.Errors) == 0 {
+		return nil
+	}
+	return m
+}
+
+func (m *Multi) Error() string {
+	if len(m.Errors) == 1 {
+		return m.Errors[0].Error()
+	}
+	parts := make([]string, len(m.Errors))
+	for i, e := range m.Errors {
+		parts[i] = e.Error()
+	}
+	return strconv.Itoa(len(m.Errors)) + " errors: " + strings.Join(parts, "; ")
+}
+
+func (m *Multi) Unwrap() []error {
+	errs := make([]error, len(m.Errors))
+	for i, e := range m.Errors {
+		errs[i] = e
+	}
+	return errs
+}
+
+// Status is the highest HTTP status among the contained errors.
+func (m *Multi) Status() int {
+	status := 0
+	for _, e := range m.Errors {
+		if s := e.Status(); s > status {
+			status = s
+		}
+	}
+	if status == 0 {
+		return http.StatusInternalServerError
+	}
+	return status
+}
+
+func (m *Multi) MarshalJSON() ([]byte, error) {
+	items := make([]publicError, len(m.Errors))
+	for i, e := range m.Errors {
+		items[i] = e.public()
+	}
+	return json.Marshal(items)
+}
@@ -0,0 +1,53 @@
+package errors
+
+import (
+	"sort"
+	"sync"
+)
+
+var registry = struct {
+	sync.Mutex
+	codes map[string][]*AppError
+}{codes: map[string][]*AppError{}}
+
+// Define registers code with its HTTP status and default message and returns
+// the template to derive errors from (the With* methods copy it). Defining a
+// code again with the same status shares the registration, with message as the
+// template's message. Defining it with another status registers a second
+// entry, which Registered reports so tests can catch the collision.
+func Define(code string, status int, message string) *AppError {
+	registry.Lock()
+	defer registry.Unlock()
+	for _, e := range registry.codes[code] {
+		if e.HTTPStatus != status {
+			continue
+		}
+		if e.Message != message {
+			return e.WithMessage(message)
+		}
+		return e
+	}
+	e := New().WithHTTPStatus(status).WithCode(code).WithMessage(message)
+	registry.codes[code] = append(registry.codes[code], e)
+	return e
+}
+
+// Registered lists the defined error templates sorted by code, then status. A
+// code listed more than once was defined with different statuses.
+func Registered() []*AppError {
+	registry.Lock()
+	defer registry.Unlock()
+	var out []*AppError
+	for _, defs := range registry.codes {
+		for _, e := range defs {
+			out = append(out, e.clone())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Code != out[j].Code {
+			return out[i].Code < out[j].Code
+		}
+		return out[i].HTTPStatus < out[j].HTTPStatus
+	})
+	return out
+}
@@ -0,0 +1,65 @@
+package errors_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/shadowofcards/go-toolkit/errors"
+
+	// Imported for the error codes their sentinels define.
+	_ "github.com/shadowofcards/go-toolkit/http"
+	_ "github.com/shadowofcards/go-toolkit/httpclient"
+	_ "github.com/shadowofcards/go-toolkit/jwt"
+	_ "github.com/shadowofcards/go-toolkit/messaging"
+	_ "github.com/shadowofcards/go-toolkit/middlewares"
+	_ "github.com/shadowofcards/go-toolkit/websocket"
+)
+
+func TestRegisteredCodesDoNotCollide(t *testing.T) {
+	status := map[string]int{}
+	for _, e := range errors.Registered() {
+		if strings.HasPrefix(e.Code, "TEST_") {
+			continue // defined by the tests below
+		}
+		if prev, ok := status[e.Code]; ok && prev != e.HTTPStatus {
+			t.Errorf("code %s is defined with statuses %d and %d", e.Code, prev, e.HTTPStatus)
+			continue
+		}
+		status[e.Code] = e.HTTPStatus
+	}
+	if len(status) == 0 {
+		t.Fatal("no error codes registered")
+	}
+}
+
+func TestDefineSharesTemplate(t *testing.T) {
+	a := errors.Define("TEST_DEFINE_SHARED", http.StatusConflict, "conflict")
+	b := errors.Define("TEST_DEFINE_SHARED", http.StatusConflict, "conflict")
+	if a != b {
+		t.Fatal("redefining a code with the same status returned a new template")
+	}
+
+	c := errors.Define("TEST_DEFINE_SHARED", http.StatusConflict, "other message")
+	if c.Message != "other message" || c.Status() != http.StatusConflict {
+		t.Fatalf("got %q/%d, want the new message with the shared status", c.Message, c.Status())
+	}
+	if !errors.HasCode(c, "TEST_DEFINE_SHARED") {
+		t.Fatal("template lost its code")
+	}
+}
+
+func TestDefineReportsStatusCollision(t *testing.T) {
+	errors.Define("TEST_DEFINE_COLLISION", http.StatusBadRequest, "bad")
+	errors.Define("TEST_DEFINE_COLLISION", http.StatusNotFound, "missing")
+
+	var statuses []int
+	for _, e := range errors.Registered() {
+		if e.Code == "TEST_DEFINE_COLLISION" {
+			statuses = append(statuses, e.HTTPStatus)
+		}
+	}
+	if len(statuses) != 2 || statuses[0] != http.StatusBadRequest || statuses[1] != http.StatusNotFound {
+		t.Fatalf("Registered() statuses = %v, want [400 404]", statuses)
+	}
+}
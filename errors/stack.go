@@ -0,0 +1,63 @@
+package errors
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const maxStackDepth = 32
+
+var (
+	captureStack atomic.Bool
+	development  atomic.Bool
+)
+
+// CaptureStack turns stack capture on or off for every AppError created or
+// derived (With* calls) afterwards. It is off by default, leaving the hot path
+// allocation-free.
+func CaptureStack(enable bool) { captureStack.Store(enable) }
+
+// SetDevelopment makes Error() append the captured stack trace. Leave it off
+// in production so traces stay out of messages.
+func SetDevelopment(dev bool) { development.Store(dev) }
+
+// NewWithStack is New with the stack captured regardless of CaptureStack.
+func NewWithStack() *AppError {
+	e := New()
+	e.stack = callers(3)
+	return e
+}
+
+// StackTrace returns the program counters captured when e was created or last
+// derived, or nil when capture was off.
+func (e *AppError) StackTrace() []uintptr { return e.stack }
+
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip, pcs)
+	return pcs[:n]
+}
+
+// FormatStack renders pcs as "function\n\tfile:line" lines.
+func FormatStack(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		b.WriteString(f.Function)
+		b.WriteString("\n\t")
+		b.WriteString(f.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(f.Line))
+		if !more {
+			break
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
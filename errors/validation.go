@@ -0,0 +1,31 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrValidation is the template returned by FromValidation.
+var ErrValidation = Define("VALIDATION_ERROR", http.StatusBadRequest, "validation failed")
+
+// FromValidation converts validator.ValidationErrors in err's chain into a
+// VALIDATION_ERROR whose context maps each field to the failed rule, including
+// its parameter (e.g. "validation failed on 'min=3'"). It returns nil for
+// other errors.
+func FromValidation(err error) *AppError {
+	var ve validator.ValidationErrors
+	if !errors.As(err, &ve) {
+		return nil
+	}
+	e := ErrValidation.WithError(err)
+	for _, f := range ve {
+		rule := f.Tag()
+		if p := f.Param(); p != "" {
+			rule += "=" + p
+		}
+		e.Context[f.Field()] = "validation failed on '" + rule + "'"
+	}
+	return e
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/shadowofcards/go-toolkit/contexts"
+)
+
+type debugAuthConfig struct {
+	enabled    bool
+	adminPerm  string
+	allowQuery bool
+}
+
+type DebugAuthOption func(*debugAuthConfig)
+
+// WithDebugAuthEnabled turns the debug endpoint on. It answers 404 otherwise.
+func WithDebugAuthEnabled(enabled bool) DebugAuthOption {
+	return func(c *debugAuthConfig) { c.enabled = enabled }
+}
+
+// WithDebugAdminPermission sets the permission a caller needs to use the
+// endpoint (default "admin").
+func WithDebugAdminPermission(perm string) DebugAuthOption {
+	return func(c *debugAuthConfig) { c.adminPerm = perm }
+}
+
+// WithDebugRequireQuery lets callers add permissions to check with
+// ?require=a,b, e.g. to test a route other than the debug endpoint's own.
+func WithDebugRequireQuery(allow bool) DebugAuthOption {
+	return func(c *debugAuthConfig) { c.allowQuery = allow }
+}
+
+// DebugAuthHandler reports the permissions required by the route (see
+// UsedPermissions, plus ?require=a,b with WithDebugRequireQuery), what the
+// caller was granted and the resulting decision, to help diagnose 403s. It is disabled unless
+// WithDebugAuthEnabled(true) is passed, and restricted to callers holding the
+// admin permission.
+func DebugAuthHandler(opts ...DebugAuthOption) fiber.Handler {
+	cfg := &debugAuthConfig{adminPerm: "admin"}
+	for _, o := range opts {
+		o(cfg)
+	}
+	return func(c fiber.Ctx) error {
+		if !cfg.enabled {
+			return fiber.ErrNotFound
+		}
+		granted, ok := grantedPermissions(c)
+		if !ok {
+			return ErrUnauthorized
+		}
+		if !has(granted, cfg.adminPerm) {
+			return ErrForbidden
+		}
+
+		required := append([]string{}, UsedPermissions(c)...)
+		if q := c.Query("require"); cfg.allowQuery && q != "" {
+			for _, p := range strings.Split(q, ",") {
+				if p = strings.TrimSpace(p); p != "" {
+					required = append(required, p)
+				}
+			}
+		}
+		missing := make([]string, 0)
+		for _, p := range required {
+			if !has(granted, p) {
+				missing = append(missing, p)
+			}
+		}
+
+		roles, _ := c.Locals("roles").([]string)
+		if roles == nil {
+			roles, _ = c.Context().Value(contexts.KeyUserRoles).([]string)
+		}
+
+		return c.JSON(fiber.Map{
+			"required": required,
+			"granted": fiber.Map{
+				"roles":       roles,
+				"permissions": granted,
+			},
+			"missing": missing,
+			"allowed": len(missing) == 0,
+		})
+	}
+}
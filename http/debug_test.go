@@ -0,0 +1,45 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestDebugAuthHandlerSeesRoutePermissions(t *testing.T) {
+	app := rbacApp([]string{"admin", "reports:read"}, func(app *fiber.App) {
+		RouterProtected(app, http.MethodGet, "/x", DebugAuthHandler(WithDebugAuthEnabled(true)), "reports:read")
+	})
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/x?require=reports:write", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	var body struct {
+		Required []string `json:"required"`
+		Allowed  bool     `json:"allowed"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	// ?require= is ignored without WithDebugRequireQuery.
+	if len(body.Required) != 1 || body.Required[0] != "reports:read" || !body.Allowed {
+		t.Fatalf("got %+v, want required [reports:read] and allowed", body)
+	}
+}
+
+func TestDebugAuthHandlerDisabledByDefault(t *testing.T) {
+	app := rbacApp([]string{"admin"}, func(app *fiber.App) {
+		app.Get("/x", DebugAuthHandler())
+	})
+	if got := status(t, app); got != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", got, http.StatusNotFound)
+	}
+}
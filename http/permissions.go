@@ -0,0 +1,34 @@
+package http
+
+import "github.com/gofiber/fiber/v3"
+
+// UsedPermissionsKey is the Locals key where RouterProtected records the
+// permissions required by the matched route. Prefer UsedPermissions over reading
+// the key directly.
+const UsedPermissionsKey = "used_permissions"
+
+// UsedPermissions returns the permissions required by the current route, or nil
+// when the route was not registered through RouterProtected.
+func UsedPermissions(c fiber.Ctx) []string {
+	perms, _ := c.Locals(UsedPermissionsKey).([]string)
+	return perms
+}
+
+// HasAllRequired reports whether the caller holds every permission required by
+// the current route. Routes without required permissions always return true.
+func HasAllRequired(c fiber.Ctx) bool {
+	required := UsedPermissions(c)
+	if len(required) == 0 {
+		return true
+	}
+	granted, ok := grantedPermissions(c)
+	if !ok {
+		return false
+	}
+	for _, p := range required {
+		if !has(granted, p) {
+			return false
+		}
+	}
+	return true
+}
@@ -2,37 +2,139 @@ package http
 
 import (
 	"net/http"
+	"sync/atomic"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/shadowofcards/go-toolkit/contexts"
 	apperrors "github.com/shadowofcards/go-toolkit/errors"
+	gtkjwt "github.com/shadowofcards/go-toolkit/jwt"
 )
 
+// RBAC error codes follow the toolkit-wide UPPER_SNAKE convention. They were
+// lowercase ("unauthorized", "forbidden") before; clients matching on the old
+// codes must be updated.
 var (
-	ErrUnauthorized = apperrors.New().
-			WithHTTPStatus(http.StatusUnauthorized).
-			WithCode("unauthorized").
-			WithMessage("unauthorized")
-
-	ErrForbidden = apperrors.New().
-			WithHTTPStatus(http.StatusForbidden).
-			WithCode("forbidden").
-			WithMessage("forbidden")
+	ErrUnauthorized = apperrors.Define("UNAUTHORIZED", http.StatusUnauthorized, "unauthorized")
+
+	ErrForbidden = apperrors.Define("FORBIDDEN", http.StatusForbidden, "forbidden")
 )
 
 func RequirePermission(permission string) fiber.Handler {
 	return func(c fiber.Ctx) error {
-		raw := c.Locals("claims")
-		claims, ok := raw.(jwt.MapClaims)
+		perms, ok := grantedPermissions(c)
 		if !ok {
 			return ErrUnauthorized
 		}
-		permsRaw, _ := claims["perms"].([]interface{})
-		for _, p := range permsRaw {
-			if s, ok := p.(string); ok && s == permission {
+		if has(perms, permission) {
+			return c.Next()
+		}
+		return ErrForbidden
+	}
+}
+
+// RequireAnyPermission lets the request through when the caller holds at least
+// one of perms.
+func RequireAnyPermission(perms ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		granted, ok := grantedPermissions(c)
+		if !ok {
+			return ErrUnauthorized
+		}
+		for _, p := range perms {
+			if has(granted, p) {
 				return c.Next()
 			}
 		}
-		return ErrForbidden
+		return ErrForbidden.WithContext("required_any", perms)
+	}
+}
+
+// RequireAllPermissions lets the request through when the caller holds every
+// one of perms.
+func RequireAllPermissions(perms ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		granted, ok := grantedPermissions(c)
+		if !ok {
+			return ErrUnauthorized
+		}
+		var missing []string
+		for _, p := range perms {
+			if !has(granted, p) {
+				missing = append(missing, p)
+			}
+		}
+		if len(missing) > 0 {
+			return ErrForbidden.WithContext("missing", missing)
+		}
+		return c.Next()
+	}
+}
+
+// PermissionExtractor returns the permissions granted to the caller; ok is
+// false when the caller is not authenticated.
+type PermissionExtractor func(c fiber.Ctx) (perms []string, ok bool)
+
+type rbacConfig struct {
+	extractor PermissionExtractor
+	super     string
+}
+
+var rbac atomic.Pointer[rbacConfig]
+
+func init() { rbac.Store(&rbacConfig{}) }
+
+// SetPermissionExtractor replaces how the guards read the caller's
+// permissions, for claim shapes other than Keycloak's. nil restores the
+// default: JWT claims in Locals, then contexts.KeyUserRoles.
+func SetPermissionExtractor(fn PermissionExtractor) {
+	cfg := *rbac.Load()
+	cfg.extractor = fn
+	rbac.Store(&cfg)
+}
+
+// SetSuperPermission sets a permission or role that satisfies every check, e.g.
+// "service" to let service-token callers through. It is off by default and ""
+// disables it again. Realm roles count as permissions, so pick a value no user
+// token carries.
+func SetSuperPermission(perm string) {
+	cfg := *rbac.Load()
+	cfg.super = perm
+	rbac.Store(&cfg)
+}
+
+// has reports whether granted includes perm or the super permission.
+func has(granted []string, perm string) bool {
+	if contains(granted, perm) {
+		return true
+	}
+	super := rbac.Load().super
+	return super != "" && contains(granted, super)
+}
+
+// grantedPermissions returns the caller's permissions: the configured
+// extractor's, or the "perms" and realm_access.roles claims, or else the roles
+// stored under contexts.KeyUserRoles (set by both auth paths). ok is false
+// when none are present.
+func grantedPermissions(c fiber.Ctx) (perms []string, ok bool) {
+	if fn := rbac.Load().extractor; fn != nil {
+		return fn(c)
+	}
+	if claims, ok := c.Locals("claims").(jwt.MapClaims); ok {
+		parsed := gtkjwt.ClaimsFromMap(claims)
+		return append(parsed.Permissions, parsed.Roles...), true
+	}
+	if roles, ok := c.Context().Value(contexts.KeyUserRoles).([]string); ok {
+		return roles, true
+	}
+	return nil, false
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
 	}
+	return false
 }
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/shadowofcards/go-toolkit/contexts"
+)
+
+// Role marks a RouterProtected requirement as a role rather than a permission:
+//
+//	RouterProtected(r, "GET", "/x", h, "reports:read", Role("auditor"))
+type Role string
+
+// RequireRole lets the request through when the caller has role.
+func RequireRole(role string) fiber.Handler {
+	return requireRoles(true, role)
+}
+
+// RequireAnyRole lets the request through when the caller has at least one of
+// roles. With no roles it rejects every request.
+func RequireAnyRole(roles ...string) fiber.Handler {
+	return requireRoles(false, roles...)
+}
+
+// requireRoles checks roles with AND (all) or OR semantics. The super
+// permission (see SetSuperPermission) satisfies any role. An empty roles list
+// is a misconfiguration and fails closed.
+func requireRoles(all bool, roles ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		granted, ok := grantedRoles(c)
+		if !ok {
+			return ErrUnauthorized
+		}
+		if len(roles) == 0 {
+			return ErrForbidden
+		}
+		var missing []string
+		for _, r := range roles {
+			if has(granted, r) {
+				if !all {
+					return c.Next()
+				}
+				continue
+			}
+			missing = append(missing, r)
+		}
+		if len(missing) == 0 {
+			return c.Next()
+		}
+		if all {
+			return ErrForbidden.WithContext("missing_roles", missing)
+		}
+		return ErrForbidden.WithContext("required_any_role", roles)
+	}
+}
+
+// grantedRoles returns the roles set by the auth middlewares, from the request
+// context or, failing that, Locals.
+func grantedRoles(c fiber.Ctx) ([]string, bool) {
+	if roles, ok := c.Context().Value(contexts.KeyUserRoles).([]string); ok {
+		return roles, true
+	}
+	roles, ok := c.Locals("roles").([]string)
+	return roles, ok
+}
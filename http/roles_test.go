@@ -0,0 +1,126 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/shadowofcards/go-toolkit/contexts"
+	apperrors "github.com/shadowofcards/go-toolkit/errors"
+)
+
+// rbacApp serves GET /x with handlers after a middleware that stores roles in
+// the request context, as the auth middlewares do. nil roles means anonymous.
+func rbacApp(roles []string, register func(app *fiber.App)) *fiber.App {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c fiber.Ctx, err error) error {
+			if ae, ok := apperrors.FromError(err); ok {
+				return c.SendStatus(ae.Status())
+			}
+			if fe, ok := err.(*fiber.Error); ok {
+				return c.SendStatus(fe.Code)
+			}
+			return c.SendStatus(http.StatusInternalServerError)
+		},
+	})
+	app.Use(func(c fiber.Ctx) error {
+		if roles != nil {
+			c.SetContext(context.WithValue(c.Context(), contexts.KeyUserRoles, roles))
+		}
+		return c.Next()
+	})
+	register(app)
+	return app
+}
+
+func ok(c fiber.Ctx) error { return c.SendStatus(http.StatusNoContent) }
+
+func status(t *testing.T, app *fiber.App) int {
+	t.Helper()
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/x", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
+
+var serviceRoles = []string{"service"} // set by the service-token auth path
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name  string
+		roles []string
+		guard fiber.Handler
+		want  int
+	}{
+		{"service token has service", serviceRoles, RequireRole("service"), http.StatusNoContent},
+		{"service token lacks admin", serviceRoles, RequireRole("admin"), http.StatusForbidden},
+		{"any role matches service", serviceRoles, RequireAnyRole("admin", "service"), http.StatusNoContent},
+		{"any role without match", []string{"player"}, RequireAnyRole("admin", "service"), http.StatusForbidden},
+		{"any role with none configured", serviceRoles, RequireAnyRole(), http.StatusForbidden},
+		{"anonymous", nil, RequireRole("service"), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := rbacApp(tt.roles, func(app *fiber.App) { app.Get("/x", ok, tt.guard) })
+			if got := status(t, app); got != tt.want {
+				t.Fatalf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireRoleReadsLocals(t *testing.T) {
+	app := rbacApp(nil, func(app *fiber.App) {
+		app.Use(func(c fiber.Ctx) error {
+			c.Locals("roles", serviceRoles)
+			return c.Next()
+		})
+		app.Get("/x", ok, RequireRole("service"))
+	})
+	if got := status(t, app); got != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", got, http.StatusNoContent)
+	}
+}
+
+func TestRouterProtectedMixesRolesAndPermissions(t *testing.T) {
+	tests := []struct {
+		name  string
+		roles []string
+		want  int
+	}{
+		{"role and permission", []string{"auditor", "reports:read"}, http.StatusNoContent},
+		{"role only", []string{"auditor"}, http.StatusForbidden},
+		{"permission only", []string{"reports:read"}, http.StatusForbidden},
+		{"service token", serviceRoles, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := rbacApp(tt.roles, func(app *fiber.App) {
+				RouterProtected(app, http.MethodGet, "/x", ok, "reports:read", Role("auditor"))
+			})
+			if got := status(t, app); got != tt.want {
+				t.Fatalf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceSuperPermissionIsOptIn(t *testing.T) {
+	register := func(app *fiber.App) {
+		RouterProtected(app, http.MethodGet, "/x", ok, "reports:read", Role("auditor"))
+	}
+	if got := status(t, rbacApp(serviceRoles, register)); got != http.StatusForbidden {
+		t.Fatalf("without SetSuperPermission: status = %d, want %d", got, http.StatusForbidden)
+	}
+
+	SetSuperPermission("service")
+	t.Cleanup(func() { SetSuperPermission("") })
+	if got := status(t, rbacApp(serviceRoles, register)); got != http.StatusNoContent {
+		t.Fatalf("with SetSuperPermission(\"service\"): status = %d, want %d", got, http.StatusNoContent)
+	}
+}
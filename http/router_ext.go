@@ -2,10 +2,20 @@ package http
 
 import (
 	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
 
 	"github.com/gofiber/fiber/v3"
+	apperrors "github.com/shadowofcards/go-toolkit/errors"
 )
 
+// ErrInvalidMethods is returned by RouterProtectedE when the methods argument
+// cannot be read as HTTP method names.
+var ErrInvalidMethods = apperrors.Define("INVALID_ROUTE_METHODS", http.StatusInternalServerError, "route methods must be a string, a fmt.Stringer or a slice of them")
+
+// RouterProtected is RouterProtectedE for route setup code; it panics when the
+// methods argument is invalid.
 func RouterProtected(
 	r fiber.Router,
 	methodsArg interface{},
@@ -13,38 +23,95 @@ func RouterProtected(
 	handler fiber.Handler,
 	permissions ...any,
 ) fiber.Router {
+	router, err := RouterProtectedE(r, methodsArg, path, handler, permissions...)
+	if err != nil {
+		panic(fmt.Sprintf("RouterProtected: %v", err))
+	}
+	return router
+}
 
-	var methods []string
-	switch m := methodsArg.(type) {
-	case string:
-		methods = []string{m}
-	case []string:
-		methods = m
-	default:
-		panic(fmt.Sprintf("RouterProtected: methods must be string or []string, got %T", methodsArg))
+// RouterProtectedE registers handler behind every requirement given: Role
+// values are checked as roles, anything else as a permission (fmt.Sprint).
+// methodsArg may be a method name, a []string, any string-kinded type or
+// fmt.Stringer, or a slice of those; anything else registers nothing and
+// returns ErrInvalidMethods.
+func RouterProtectedE(
+	r fiber.Router,
+	methodsArg interface{},
+	path string,
+	handler fiber.Handler,
+	permissions ...any,
+) (fiber.Router, error) {
+	methods, ok := methodNames(methodsArg)
+	if !ok {
+		return nil, ErrInvalidMethods.
+			WithContext("type", fmt.Sprintf("%T", methodsArg)).
+			WithContext("path", path)
 	}
 
 	if len(permissions) == 0 {
-		return r.Add(methods, path, handler)
+		return r.Add(methods, path, handler), nil
 	}
 
-	permStrs := make([]string, len(permissions))
-	for i, p := range permissions {
-		permStrs[i] = fmt.Sprint(p)
+	var permStrs, roles []string
+	for _, p := range permissions {
+		if role, ok := p.(Role); ok {
+			roles = append(roles, string(role))
+			continue
+		}
+		permStrs = append(permStrs, fmt.Sprint(p))
 	}
 
-	mws := make([]fiber.Handler, 0, len(permStrs)+2)
-
-	mws = append(mws, func(c fiber.Ctx) error {
-		c.Locals("used_permissions", permStrs)
+	record := func(c fiber.Ctx) error {
+		c.Locals(UsedPermissionsKey, permStrs)
 		return c.Next()
-	})
-
-	for _, perm := range permStrs {
-		mws = append(mws, RequirePermission(perm))
+	}
+	mws := []fiber.Handler{record}
+	if len(roles) > 0 {
+		mws = append(mws, requireRoles(true, roles...))
+	}
+	if len(permStrs) > 0 {
+		mws = append(mws, RequireAllPermissions(permStrs...))
 	}
 
-	mws = append(mws, handler)
+	// fiber runs the middleware arguments first and the handler argument last.
+	return r.Add(methods, path, handler, mws...), nil
+}
 
-	return r.Add(methods, path, mws[0], mws[1:]...)
+// methodNames reads v as one or more upper-cased method names.
+func methodNames(v interface{}) ([]string, bool) {
+	if m, ok := methodName(v); ok {
+		return []string{m}, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice || rv.Len() == 0 {
+		return nil, false
+	}
+	out := make([]string, rv.Len())
+	for i := range out {
+		m, ok := methodName(rv.Index(i).Interface())
+		if !ok {
+			return nil, false
+		}
+		out[i] = m
+	}
+	return out, true
+}
+
+func methodName(v interface{}) (string, bool) {
+	var s string
+	switch m := v.(type) {
+	case string:
+		s = m
+	case fmt.Stringer:
+		s = m.String()
+	default:
+		rv := reflect.ValueOf(v)
+		if !rv.IsValid() || rv.Kind() != reflect.String {
+			return "", false
+		}
+		s = rv.String()
+	}
+	s = strings.ToUpper(strings.TrimSpace(s))
+	return s, s != ""
 }
@@ -0,0 +1,100 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                                Body logging                                */
+/* -------------------------------------------------------------------------- */
+
+type bodyLogCfg struct {
+	maxBytes int
+	redact   map[string]struct{}
+}
+
+// WithBodyLogging logs request and response bodies of Do at debug level, cut
+// to maxBytes. Values of the given keys (matched case-insensitively, at any
+// depth in JSON, or as form fields) are replaced by "***"; with redactKeys set,
+// bodies that are neither JSON nor form-encoded are logged by size only. Needs
+// a logger (WithLogger).
+func WithBodyLogging(maxBytes int, redactKeys ...string) Option {
+	return func(c *BaseClient) {
+		cfg := &bodyLogCfg{maxBytes: maxBytes, redact: make(map[string]struct{}, len(redactKeys))}
+		for _, k := range redactKeys {
+			cfg.redact[strings.ToLower(k)] = struct{}{}
+		}
+		c.bodyLog = cfg
+	}
+}
+
+func (c *BaseClient) logsBodies() bool { return c.bodyLog != nil && c.log != nil }
+
+func (c *BaseClient) logBody(ctx context.Context, msg, fullURL, contentType string, body []byte) {
+	c.log.DebugCtx(ctx, msg,
+		zap.String("url", fullURL),
+		zap.Int("size", len(body)),
+		zap.String("body", c.bodyLog.render(contentType, body)),
+	)
+}
+
+func (b *bodyLogCfg) render(contentType string, body []byte) string {
+	out := body
+	if len(b.redact) > 0 && len(body) > 0 {
+		red, ok := b.redactBody(contentType, body)
+		if !ok {
+			return "(not logged)"
+		}
+		out = red
+	}
+	if b.maxBytes > 0 && len(out) > b.maxBytes {
+		return string(out[:b.maxBytes]) + "...(truncated)"
+	}
+	return string(out)
+}
+
+// redactBody redacts a JSON or form-encoded body; ok is false for anything else.
+func (b *bodyLogCfg) redactBody(contentType string, body []byte) (out []byte, ok bool) {
+	var v any
+	if err := json.Unmarshal(body, &v); err == nil {
+		red, err := json.Marshal(b.redactValue(v))
+		return red, err == nil
+	}
+	if mt, _, _ := mime.ParseMediaType(contentType); mt != "application/x-www-form-urlencoded" {
+		return nil, false
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, false
+	}
+	for k := range form {
+		if _, ok := b.redact[strings.ToLower(k)]; ok {
+			form[k] = []string{"***"}
+		}
+	}
+	return []byte(form.Encode()), true
+}
+
+func (b *bodyLogCfg) redactValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if _, ok := b.redact[strings.ToLower(k)]; ok {
+				t[k] = "***"
+				continue
+			}
+			t[k] = b.redactValue(val)
+		}
+	case []any:
+		for i, val := range t {
+			t[i] = b.redactValue(val)
+		}
+	}
+	return v
+}
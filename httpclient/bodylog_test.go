@@ -0,0 +1,45 @@
+package httpclient
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBodyLogRedaction(t *testing.T) {
+	c := &BaseClient{}
+	WithBodyLogging(0, "password", "token")(c)
+	b := c.bodyLog
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"json", "application/json", `{"user":"ann","Password":"hunter2","nested":{"token":"t"}}`,
+			`{"Password":"***","nested":{"token":"***"},"user":"ann"}`},
+		{"form", "application/x-www-form-urlencoded; charset=utf-8", "user=ann&password=hunter2",
+			"password=%2A%2A%2A&user=ann"},
+		{"plain text", "text/plain", "password=hunter2", "(not logged)"},
+		{"empty", "application/json", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := b.render(tt.contentType, []byte(tt.body))
+			if got != tt.want {
+				t.Fatalf("render = %q, want %q", got, tt.want)
+			}
+			if strings.Contains(got, "hunter2") {
+				t.Fatal("secret leaked into the log")
+			}
+		})
+	}
+}
+
+func TestBodyLogWithoutRedactKeysTruncates(t *testing.T) {
+	c := &BaseClient{}
+	WithBodyLogging(8)(c)
+	if got := c.bodyLog.render("text/plain", []byte("0123456789")); got != "01234567...(truncated)" {
+		t.Fatalf("render = %q", got)
+	}
+}
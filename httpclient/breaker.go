@@ -0,0 +1,172 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/shadowofcards/go-toolkit/errors"
+	"go.uber.org/zap"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                              Circuit breaker                               */
+/* -------------------------------------------------------------------------- */
+
+var ErrCircuitOpen = errors.Define("CIRCUIT_OPEN", http.StatusServiceUnavailable, "upstream circuit is open")
+
+type circuitState int
+
+// Values reported by the http_client_circuit_state gauge.
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeIgnored
+)
+
+type hostCircuit struct {
+	state    circuitState
+	failures int
+	openedAt time.Time
+	// probe is the token of the call holding the half-open probe, 0 if none.
+	probe uint64
+}
+
+type breaker struct {
+	threshold    int
+	openDuration time.Duration
+
+	mu        sync.Mutex
+	hosts     map[string]*hostCircuit
+	lastProbe uint64
+}
+
+// WithCircuitBreaker stops calling a host after failureThreshold consecutive
+// failures (transport errors or 5xx). While open, calls fail fast with
+// CIRCUIT_OPEN; after openDuration a single probe is let through and its result
+// closes or re-opens the circuit. State is kept per host.
+func WithCircuitBreaker(failureThreshold int, openDuration time.Duration) Option {
+	return func(c *BaseClient) {
+		if failureThreshold < 1 {
+			failureThreshold = 1
+		}
+		c.breaker = &breaker{
+			threshold:    failureThreshold,
+			openDuration: openDuration,
+			hosts:        map[string]*hostCircuit{},
+		}
+	}
+}
+
+// allow reports whether a call to host may proceed, moving an expired open
+// circuit to half-open. A call let through as the half-open probe gets a
+// non-zero token, which it passes back to done.
+func (b *breaker) allow(host string) (ok bool, probe uint64, state circuitState, changed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hc, found := b.hosts[host]
+	if !found {
+		hc = &hostCircuit{}
+		b.hosts[host] = hc
+	}
+	switch hc.state {
+	case circuitOpen:
+		if time.Since(hc.openedAt) < b.openDuration {
+			return false, 0, hc.state, false
+		}
+		hc.state = circuitHalfOpen
+		changed = true
+	case circuitHalfOpen:
+		if hc.probe != 0 {
+			return false, 0, hc.state, false
+		}
+	default:
+		return true, 0, hc.state, false
+	}
+	b.lastProbe++
+	hc.probe = b.lastProbe
+	return true, hc.probe, hc.state, changed
+}
+
+// done records a call result and returns the new state and whether it changed.
+// Once the circuit has left the closed state only the probe's result counts:
+// calls started earlier finish with outcomes that say nothing about recovery.
+func (b *breaker) done(host string, probe uint64, res outcome) (circuitState, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hc := b.hosts[host]
+	prev := hc.state
+	if hc.state != circuitClosed {
+		if probe == 0 || probe != hc.probe {
+			return hc.state, false
+		}
+		hc.probe = 0
+	}
+	switch res {
+	case outcomeSuccess:
+		hc.state, hc.failures = circuitClosed, 0
+	case outcomeFailure:
+		hc.failures++
+		if hc.state == circuitHalfOpen || hc.failures >= b.threshold {
+			hc.state, hc.openedAt = circuitOpen, time.Now()
+		}
+	}
+	return hc.state, hc.state != prev
+}
+
+// circuitAllow returns the probe token to hand to circuitDone, or
+// ErrCircuitOpen when the call must not be made.
+func (c *BaseClient) circuitAllow(ctx context.Context, host, fullURL string) (uint64, error) {
+	if c.breaker == nil {
+		return 0, nil
+	}
+	ok, probe, state, changed := c.breaker.allow(host)
+	if changed {
+		c.recordCircuit(ctx, host, state)
+	}
+	if ok {
+		return probe, nil
+	}
+	if c.log != nil {
+		c.log.WarnCtx(ctx, "circuit open, request rejected", zap.String("host", host))
+	}
+	return 0, ErrCircuitOpen.
+		WithContext("url", fullURL).
+		WithContext("host", host)
+}
+
+func (c *BaseClient) circuitDone(ctx context.Context, host string, probe uint64, res *http.Response, err error) {
+	if c.breaker == nil {
+		return
+	}
+	result := outcomeSuccess
+	switch {
+	case err != nil && ctx.Err() != nil:
+		result = outcomeIgnored
+	case err != nil, res.StatusCode >= 500:
+		result = outcomeFailure
+	}
+	if state, changed := c.breaker.done(host, probe, result); changed {
+		c.recordCircuit(ctx, host, state)
+	}
+}
+
+func (c *BaseClient) recordCircuit(ctx context.Context, host string, state circuitState) {
+	if c.log != nil && state == circuitOpen {
+		c.log.WarnCtx(ctx, "circuit opened", zap.String("host", host))
+	}
+	if c.metrics != nil {
+		c.metrics.GaugeWithTags(ctx, "http_client_circuit_state", float64(state), map[string]string{"host": host})
+	}
+}
@@ -0,0 +1,60 @@
+package httpclient
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerOnlyProbeLeavesHalfOpen(t *testing.T) {
+	const host = "api.example.com"
+	b := &breaker{threshold: 1, openDuration: time.Millisecond, hosts: map[string]*hostCircuit{}}
+
+	// A slow call starts while the circuit is closed, then another call
+	// fails and opens it.
+	ok, slow, _, _ := b.allow(host)
+	if !ok || slow != 0 {
+		t.Fatalf("closed circuit: allow = %v, %d", ok, slow)
+	}
+	b.allow(host)
+	if state, _ := b.done(host, 0, outcomeFailure); state != circuitOpen {
+		t.Fatalf("state after failure = %v, want open", state)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	ok, probe, state, _ := b.allow(host)
+	if !ok || probe == 0 || state != circuitHalfOpen {
+		t.Fatalf("expired open circuit: allow = %v, %d, %v; want a half-open probe", ok, probe, state)
+	}
+
+	if state, changed := b.done(host, slow, outcomeSuccess); changed || state != circuitHalfOpen {
+		t.Fatalf("non-probe result moved the circuit to %v", state)
+	}
+	if ok, _, _, _ := b.allow(host); ok {
+		t.Fatal("second call let through while the probe is in flight")
+	}
+
+	if state, _ := b.done(host, probe, outcomeFailure); state != circuitOpen {
+		t.Fatalf("state after failed probe = %v, want open", state)
+	}
+}
+
+func TestBreakerIgnoredProbeReleasesIt(t *testing.T) {
+	const host = "api.example.com"
+	b := &breaker{threshold: 1, openDuration: time.Millisecond, hosts: map[string]*hostCircuit{}}
+	b.allow(host)
+	b.done(host, 0, outcomeFailure)
+
+	time.Sleep(2 * time.Millisecond)
+	_, probe, _, _ := b.allow(host)
+	if state, _ := b.done(host, probe, outcomeIgnored); state != circuitHalfOpen {
+		t.Fatalf("state after canceled probe = %v, want half-open", state)
+	}
+
+	ok, next, _, _ := b.allow(host)
+	if !ok || next == 0 || next == probe {
+		t.Fatalf("allow after canceled probe = %v, %d; want a new probe", ok, next)
+	}
+	if state, _ := b.done(host, next, outcomeSuccess); state != circuitClosed {
+		t.Fatalf("state after successful probe = %v, want closed", state)
+	}
+}
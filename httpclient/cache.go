@@ -0,0 +1,128 @@
+package httpclient
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                              Response cache                                */
+/* -------------------------------------------------------------------------- */
+
+// ResponseCache stores raw response bodies keyed by method+URL.
+type ResponseCache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, body []byte, ttl time.Duration)
+}
+
+type cacheCfg struct {
+	store ResponseCache
+	ttl   time.Duration
+}
+
+// WithResponseCache caches successful (200) GET responses for ttl. A nil cache
+// uses an in-memory LRU of 1024 entries. Responses with "Cache-Control:
+// no-store" are never cached. The key ignores caller identity headers, so only
+// enable it for resources that do not vary per user or tenant.
+func WithResponseCache(cache ResponseCache, ttl time.Duration) Option {
+	return func(c *BaseClient) {
+		if cache == nil {
+			cache = NewLRUCache(1024)
+		}
+		c.cache = &cacheCfg{store: cache, ttl: ttl}
+	}
+}
+
+func cacheKey(method, url string) string {
+	return method + " " + url
+}
+
+func (c *BaseClient) cacheable(method string) bool {
+	return c.cache != nil && strings.EqualFold(method, http.MethodGet)
+}
+
+func (c *BaseClient) cacheLookup(ctx context.Context, key, path string) ([]byte, bool) {
+	body, ok := c.cache.store.Get(key)
+	if c.metrics != nil {
+		result := "miss"
+		if ok {
+			result = "hit"
+		}
+		c.metrics.IncWithTags(ctx, "http_client_cache_total", 1, map[string]string{"path": path, "result": result})
+	}
+	return body, ok
+}
+
+func (c *BaseClient) cacheStore(key string, res *http.Response, body []byte) {
+	if res.StatusCode != http.StatusOK {
+		return
+	}
+	if strings.Contains(strings.ToLower(res.Header.Get("Cache-Control")), "no-store") {
+		return
+	}
+	c.cache.store.Set(key, body, c.cache.ttl)
+}
+
+type lruEntry struct {
+	key     string
+	body    []byte
+	expires time.Time
+}
+
+// LRUCache is a size-bounded, TTL-aware in-memory ResponseCache.
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRUCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element, capacity),
+	}
+}
+
+func (l *LRUCache) Get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if time.Now().After(e.expires) {
+		l.ll.Remove(el)
+		delete(l.items, key)
+		return nil, false
+	}
+	l.ll.MoveToFront(el)
+	return e.body, true
+}
+
+func (l *LRUCache) Set(key string, body []byte, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	expires := time.Now().Add(ttl)
+	if el, ok := l.items[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.body, e.expires = body, expires
+		l.ll.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, body: body, expires: expires})
+	for l.ll.Len() > l.capacity {
+		oldest := l.ll.Back()
+		l.ll.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry).key)
+	}
+}
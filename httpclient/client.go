@@ -29,6 +29,12 @@ type (
 		appName    string
 		log        *logging.Logger
 		metrics    metrics.Recorder
+		shadow     *shadowCfg
+		cache      *cacheCfg
+		breaker    *breaker
+		errDecoder func(body []byte, status int) *errors.AppError
+		bodyLog    *bodyLogCfg
+		traceFn    func(ctx context.Context) (traceparent, tracestate string)
 	}
 
 	Option func(*BaseClient)
@@ -61,6 +67,19 @@ func WithAppName(n string) Option           { return func(c *BaseClient) { c.app
 func WithLogger(l *logging.Logger) Option   { return func(c *BaseClient) { c.log = l } }
 func WithMetrics(m metrics.Recorder) Option { return func(c *BaseClient) { c.metrics = m } }
 
+// WithTracePropagation sets where the W3C traceparent/tracestate values come
+// from (e.g. an OpenTelemetry propagator). By default they are read from
+// contexts.KeyTraceParent and contexts.KeyTraceState.
+func WithTracePropagation(fn func(ctx context.Context) (traceparent, tracestate string)) Option {
+	return func(c *BaseClient) { c.traceFn = fn }
+}
+
+// WithErrorDecoder replaces the {"error":{"code","message"}} parsing of error
+// responses (status >= 400). Returning nil falls back to a plain HTTP_<status>.
+func WithErrorDecoder(fn func(body []byte, status int) *errors.AppError) Option {
+	return func(c *BaseClient) { c.errDecoder = fn }
+}
+
 /* -------------------------------------------------------------------------- */
 /*                               Constructor                                  */
 /* -------------------------------------------------------------------------- */
@@ -80,11 +99,23 @@ func New(baseURL string, opts ...Option) *BaseClient {
 	} else if bc.httpClient.Timeout == 0 {
 		bc.httpClient.Timeout = 10 * time.Second
 	}
+	if bc.metrics == nil {
+		bc.metrics = metrics.NoOp()
+	}
 	return bc
 }
 
 /* -------------------------------------------------------------------------- */
 
+var (
+	ErrCanceled = errors.Define("CTX_CANCELED", http.StatusInternalServerError, "request canceled")
+	ErrDeadline = errors.Define("CTX_DEADLINE", http.StatusInternalServerError, "request deadline exceeded")
+
+	ErrNilHTTPClient = errors.Define("NIL_HTTP_CLIENT", http.StatusInternalServerError, "httpClient is nil – use httpclient.New or provide one via option")
+	ErrEncode        = errors.Define("ENCODE_ERROR", http.StatusInternalServerError, "failed to encode request body")
+	ErrDecode        = errors.Define("DECODE_ERROR", http.StatusInternalServerError, "failed to decode JSON")
+)
+
 type apiErrPayload struct {
 	Error struct {
 		Code    string `json:"code"`
@@ -97,32 +128,158 @@ type apiErrPayload struct {
 /* -------------------------------------------------------------------------- */
 
 func (c *BaseClient) Do(ctx context.Context, method, path string, body io.Reader, v any) error {
+	return c.do(ctx, method, path, body, v, nil)
+}
+
+// DoWithHeaders is Do with extra headers for this call only. They are applied
+// on top of the defaults, so a caller Content-Type replaces application/json.
+func (c *BaseClient) DoWithHeaders(ctx context.Context, method, path string, body io.Reader, v any, headers http.Header) error {
+	return c.do(ctx, method, path, body, v, headers)
+}
+
+func (c *BaseClient) do(ctx context.Context, method, path string, body io.Reader, v any, headers http.Header) error {
+	fullURL := c.baseURL + path
+	if err := c.precheck(ctx, fullURL); err != nil {
+		return err
+	}
+
+	var ck string
+	if c.cacheable(method) {
+		ck = cacheKey(http.MethodGet, fullURL)
+		if cached, ok := c.cacheLookup(ctx, ck, path); ok {
+			return c.decode(ctx, cached, v, fullURL)
+		}
+	}
+
+	shadowed := c.sampleShadow()
+	var reqBody []byte
+	if (shadowed || c.logsBodies()) && body != nil {
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(body); err != nil {
+			return errors.New().
+				WithError(err).
+				WithMessage("failed to read request body").
+				WithContext("url", fullURL)
+		}
+		reqBody = buf.Bytes()
+		body = bytes.NewReader(reqBody)
+	}
+
+	req, err := c.newRequest(ctx, method, fullURL, body, headers)
+	if err != nil {
+		return err
+	}
+	if reqBody != nil && c.logsBodies() {
+		c.logBody(ctx, "HTTP request body", fullURL, req.Header.Get("Content-Type"), reqBody)
+	}
+	probe, err := c.circuitAllow(ctx, req.URL.Host, fullURL)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	res, err := c.httpClient.Do(req)
+	c.circuitDone(ctx, req.URL.Host, probe, res, err)
+	if shadowed {
+		primary := ShadowResult{Duration: time.Since(start), Err: err}
+		if res != nil {
+			primary.Status = res.StatusCode
+		}
+		c.fireShadow(ctx, req, path, reqBody, primary)
+	}
+	c.record(ctx, req, path, res, start)
+
+	if err != nil {
+		return c.transportError(ctx, err, fullURL)
+	}
+	defer res.Body.Close()
+
+	bodyBytes, _ := io.ReadAll(res.Body)
+	if c.logsBodies() {
+		c.logBody(ctx, "HTTP response body", fullURL, res.Header.Get("Content-Type"), bodyBytes)
+	}
+
+	if res.StatusCode >= 400 {
+		return c.responseError(ctx, res.StatusCode, bodyBytes, fullURL)
+	}
+
+	if err := c.decode(ctx, bodyBytes, v, fullURL); err != nil {
+		return err
+	}
+	if ck != "" {
+		c.cacheStore(ck, res, bodyBytes)
+	}
+
+	if c.log != nil {
+		c.log.InfoCtx(ctx, "HTTP request success", zap.Int("status", res.StatusCode))
+	}
+	return nil
+}
+
+// DoStream performs the request like Do but hands back the live response so the
+// caller can stream res.Body; the caller must close it. Error statuses are
+// still turned into an AppError, with the body read and closed.
+//
+// The client timeout (WithTimeout) also bounds reading the body, so use ctx
+// or a dedicated client for long downloads.
+func (c *BaseClient) DoStream(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	fullURL := c.baseURL + path
+	if err := c.precheck(ctx, fullURL); err != nil {
+		return nil, err
+	}
+
+	req, err := c.newRequest(ctx, method, fullURL, body, nil)
+	if err != nil {
+		return nil, err
+	}
+	probe, err := c.circuitAllow(ctx, req.URL.Host, fullURL)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	res, err := c.httpClient.Do(req)
+	c.circuitDone(ctx, req.URL.Host, probe, res, err)
+	c.record(ctx, req, path, res, start)
+
+	if err != nil {
+		return nil, c.transportError(ctx, err, fullURL)
+	}
+
+	if res.StatusCode >= 400 {
+		defer res.Body.Close()
+		bodyBytes, _ := io.ReadAll(res.Body)
+		return nil, c.responseError(ctx, res.StatusCode, bodyBytes, fullURL)
+	}
+
+	if c.log != nil {
+		c.log.InfoCtx(ctx, "HTTP stream opened", zap.Int("status", res.StatusCode))
+	}
+	return res, nil
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                 Internals                                  */
+/* -------------------------------------------------------------------------- */
+
+func (c *BaseClient) precheck(ctx context.Context, fullURL string) error {
 	if c.httpClient == nil {
 		if c.log != nil {
 			c.log.ErrorCtx(ctx, "nil httpClient detected")
 		}
-		return errors.New().
-			WithCode("NIL_HTTP_CLIENT").
-			WithMessage("httpClient is nil – use httpclient.New or provide one via option")
+		return ErrNilHTTPClient
 	}
 
-	fullURL := c.baseURL + path
-
-	select {
-	case <-ctx.Done():
-		return errors.New().
-			WithError(ctx.Err()).
-			WithCode("CTX_CANCELLED").
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxError(ctxErr).
+			WithError(ctxErr).
 			WithMessage("request canceled before start").
 			WithContext("url", fullURL)
-	default:
-	}
-
-	var start time.Time
-	if c.metrics != nil {
-		start = time.Now()
 	}
+	return nil
+}
 
+func (c *BaseClient) newRequest(ctx context.Context, method, fullURL string, body io.Reader, headers http.Header) (*http.Request, error) {
 	if c.log != nil {
 		c.log.InfoCtx(ctx, "HTTP request start",
 			zap.String("method", method),
@@ -135,7 +292,7 @@ func (c *BaseClient) Do(ctx context.Context, method, path string, body io.Reader
 		if c.log != nil {
 			c.log.ErrorCtx(ctx, "failed to build request", zap.Error(err))
 		}
-		return errors.New().
+		return nil, errors.New().
 			WithError(err).
 			WithMessage("failed to build HTTP request").
 			WithContext("url", fullURL)
@@ -150,11 +307,23 @@ func (c *BaseClient) Do(ctx context.Context, method, path string, body io.Reader
 	}
 	if c.appName != "" {
 		req.Header.Set("X-App-Name", c.appName)
+		req.Header.Set("X-Caller-ID", c.appName)
+	}
+	if chain, ok := ctx.Value(contexts.KeyCallerChain).(string); ok && chain != "" {
+		req.Header.Set("X-Caller-Chain", chain)
+	} else if c.appName != "" {
+		req.Header.Set("X-Caller-Chain", c.appName)
 	}
 
 	if rid, ok := ctx.Value(contexts.KeyRequestID).(string); ok {
 		req.Header.Set("X-Request-Id", rid)
 	}
+	if tp, ts := c.traceContext(ctx); tp != "" {
+		req.Header.Set("traceparent", tp)
+		if ts != "" {
+			req.Header.Set("tracestate", ts)
+		}
+	}
 	if origin, ok := ctx.Value(contexts.KeyOrigin).(string); ok {
 		req.Header.Set("X-Origin", origin)
 	}
@@ -178,91 +347,123 @@ func (c *BaseClient) Do(ctx context.Context, method, path string, body io.Reader
 	if pid, ok := ctx.Value(contexts.KeyPlayerID).(string); ok {
 		req.Header.Set("X-Player-Id", pid)
 	}
-
-	res, err := c.httpClient.Do(req)
-	if c.metrics != nil {
-		duration := float64(0)
-		if !start.IsZero() {
-			duration = time.Since(start).Seconds()
+	for k, vals := range headers {
+		req.Header.Del(k)
+		for _, v := range vals {
+			req.Header.Add(k, v)
 		}
-		tags := map[string]string{
-			"method": strings.ToUpper(method),
-			"path":   path,
-			"host":   req.URL.Host,
-		}
-		status := 0
-		if res != nil {
-			status = res.StatusCode
-		}
-		tags["status"] = statusCodeKey(status)
-		c.metrics.IncWithTags(ctx, "http_client_requests_total", 1, tags)
-		c.metrics.ObserveWithTags(ctx, "http_client_request_duration_seconds", duration, tags)
 	}
+	return req, nil
+}
 
-	if err != nil {
-		if ctxErr := ctx.Err(); ctxErr != nil {
-			code := "CTX_ERROR"
-			if ctxErr == context.Canceled {
-				code = "CTX_CANCELED"
-			} else if ctxErr == context.DeadlineExceeded {
-				code = "CTX_DEADLINE"
-			}
-			return errors.New().
-				WithError(ctxErr).
-				WithCode(code).
-				WithMessage("request canceled or timed out").
-				WithContext("url", fullURL)
-		}
-		if c.log != nil {
-			c.log.ErrorCtx(ctx, "HTTP request failed", zap.Error(err))
-		}
-		return errors.New().
-			WithError(err).
-			WithMessage("HTTP request failed").
-			WithContext("url", fullURL)
+func (c *BaseClient) traceContext(ctx context.Context) (string, string) {
+	if c.traceFn != nil {
+		return c.traceFn(ctx)
 	}
-	defer res.Body.Close()
+	tp, _ := ctx.Value(contexts.KeyTraceParent).(string)
+	ts, _ := ctx.Value(contexts.KeyTraceState).(string)
+	return tp, ts
+}
 
-	bodyBytes, _ := io.ReadAll(res.Body)
+func (c *BaseClient) record(ctx context.Context, req *http.Request, path string, res *http.Response, start time.Time) {
+	if c.metrics == nil {
+		return
+	}
+	status := 0
+	if res != nil {
+		status = res.StatusCode
+	}
+	tags := map[string]string{
+		"method": strings.ToUpper(req.Method),
+		"path":   path,
+		"host":   req.URL.Host,
+		"status": statusCodeKey(status),
+	}
+	c.metrics.IncWithTags(ctx, "http_client_requests_total", 1, tags)
+	c.metrics.ObserveWithTags(ctx, "http_client_request_duration_seconds", time.Since(start).Seconds(), tags)
+}
 
-	if res.StatusCode >= 400 {
-		var payload apiErrPayload
-		code := fmt.Sprintf("HTTP_%d", res.StatusCode)
-		msg := "service returned error"
-		if err := json.Unmarshal(bodyBytes, &payload); err == nil && payload.Error.Code != "" {
-			code = payload.Error.Code
-			msg = payload.Error.Message
-		}
-		if c.log != nil {
-			c.log.WarnCtx(ctx, "HTTP error response",
-				zap.Int("status", res.StatusCode),
-				zap.String("error_code", code),
-			)
-		}
-		return errors.New().
-			WithHTTPStatus(res.StatusCode).
-			WithCode(code).
-			WithMessage(msg).
-			WithContext("url", fullURL).
-			WithContext("status", res.StatusCode).
-			WithContext("body", string(bodyBytes))
+func (c *BaseClient) transportError(ctx context.Context, err error, fullURL string) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxError(ctxErr).WithError(ctxErr).WithContext("url", fullURL)
+	}
+	if c.log != nil {
+		c.log.ErrorCtx(ctx, "HTTP request failed", zap.Error(err))
 	}
+	return errors.New().
+		WithError(err).
+		WithMessage("HTTP request failed").
+		WithContext("url", fullURL)
+}
 
-	if v != nil {
-		if err := json.NewDecoder(bytes.NewReader(bodyBytes)).Decode(v); err != nil {
+func (c *BaseClient) responseError(ctx context.Context, status int, body []byte, fullURL string) error {
+	if c.errDecoder != nil {
+		if ae := c.errDecoder(body, status); ae != nil {
 			if c.log != nil {
-				c.log.ErrorCtx(ctx, "failed to decode response", zap.Error(err))
+				c.log.WarnCtx(ctx, "HTTP error response",
+					zap.Int("status", status),
+					zap.String("error_code", ae.ErrCode()),
+				)
 			}
-			return errors.New().
-				WithError(err).
-				WithCode("DECODE_ERROR").
-				WithMessage("failed to decode JSON").
-				WithContext("url", fullURL)
+			if ae.HTTPStatus == 0 {
+				ae = ae.WithHTTPStatus(status)
+			}
+			return ae.WithContext("url", fullURL).WithContext("status", status)
 		}
 	}
 
+	code := fmt.Sprintf("HTTP_%d", status)
+	msg := "service returned error"
+	var payload apiErrPayload
+	if c.errDecoder == nil && json.Unmarshal(body, &payload) == nil && payload.Error.Code != "" {
+		code = payload.Error.Code
+		msg = payload.Error.Message
+	}
 	if c.log != nil {
-		c.log.InfoCtx(ctx, "HTTP request success", zap.Int("status", res.StatusCode))
+		c.log.WarnCtx(ctx, "HTTP error response",
+			zap.Int("status", status),
+			zap.String("error_code", code),
+		)
+	}
+	return errors.New().
+		WithHTTPStatus(status).
+		WithCode(code).
+		WithMessage(msg).
+		WithContext("url", fullURL).
+		WithContext("status", status).
+		WithContext("body", string(body))
+}
+
+// ctxError picks the sentinel matching err, which is ctx.Err() of a done
+// context: ErrDeadline for an expired deadline, ErrCanceled otherwise.
+func ctxError(err error) *errors.AppError {
+	if err == context.DeadlineExceeded {
+		return ErrDeadline
+	}
+	return ErrCanceled
+}
+
+// allowEmpty wraps a decode target that keeps its zero value on an empty body
+// instead of failing with DECODE_ERROR; the typed helpers use it.
+type allowEmpty struct{ v any }
+
+func (c *BaseClient) decode(ctx context.Context, body []byte, v any, fullURL string) error {
+	if e, ok := v.(allowEmpty); ok {
+		if len(bytes.TrimSpace(body)) == 0 {
+			return nil
+		}
+		v = e.v
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(v); err != nil {
+		if c.log != nil {
+			c.log.ErrorCtx(ctx, "failed to decode response", zap.Error(err))
+		}
+		return ErrDecode.
+			WithError(err).
+			WithContext("url", fullURL)
 	}
 	return nil
 }
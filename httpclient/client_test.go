@@ -0,0 +1,96 @@
+package httpclient_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shadowofcards/go-toolkit/errors"
+	"github.com/shadowofcards/go-toolkit/httpclient"
+	"github.com/shadowofcards/go-toolkit/httpclient/httpclienttest"
+)
+
+func noContent(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+
+func TestDoWithHeadersReachesTheRequest(t *testing.T) {
+	c, rec := httpclienttest.NewRecordingClient(http.HandlerFunc(noContent), httpclient.WithAuthToken("svc-token"))
+
+	h := http.Header{}
+	h.Set("Idempotency-Key", "abc-123")
+	h.Set("X-Trace-Flags", "01")
+	if err := c.DoWithHeaders(context.Background(), http.MethodPost, "/orders", strings.NewReader(`{}`), nil, h); err != nil {
+		t.Fatal(err)
+	}
+
+	got := rec.Last().Header
+	for k, want := range map[string]string{
+		"Idempotency-Key": "abc-123",
+		"X-Trace-Flags":   "01",
+		"X-Service-Token": "svc-token",
+		"Content-Type":    "application/json",
+	} {
+		if v := got.Get(k); v != want {
+			t.Errorf("%s = %q, want %q", k, v, want)
+		}
+	}
+}
+
+func TestDoWithHeadersOverridesContentType(t *testing.T) {
+	c, rec := httpclienttest.NewRecordingClient(http.HandlerFunc(noContent))
+
+	h := http.Header{}
+	h.Set("Content-Type", "text/csv")
+	if err := c.DoWithHeaders(context.Background(), http.MethodPost, "/import", strings.NewReader("a,b\n"), nil, h); err != nil {
+		t.Fatal(err)
+	}
+
+	req := rec.Last()
+	if ct := req.Header.Get("Content-Type"); ct != "text/csv" {
+		t.Fatalf("Content-Type = %q, want text/csv", ct)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != "a,b\n" {
+		t.Fatalf("body = %q, want the CSV payload", body)
+	}
+}
+
+func TestDoWithHeadersDoesNotLeakToLaterCalls(t *testing.T) {
+	c, rec := httpclienttest.NewRecordingClient(http.HandlerFunc(noContent))
+
+	h := http.Header{}
+	h.Set("Idempotency-Key", "once")
+	if err := c.DoWithHeaders(context.Background(), http.MethodPost, "/a", nil, nil, h); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Do(context.Background(), http.MethodPost, "/b", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if v := rec.Last().Header.Get("Idempotency-Key"); v != "" {
+		t.Fatalf("Idempotency-Key leaked into the next call: %q", v)
+	}
+}
+
+func TestDoneContextCodes(t *testing.T) {
+	c := httpclienttest.NewTestClient(http.HandlerFunc(noContent))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	for name, tc := range map[string]struct {
+		ctx  context.Context
+		code string
+	}{
+		"canceled": {canceled, "CTX_CANCELED"},
+		"expired":  {expired, "CTX_DEADLINE"},
+	} {
+		err := c.Do(tc.ctx, http.MethodGet, "/", nil, nil)
+		if !errors.HasCode(err, tc.code) {
+			t.Errorf("%s: Do = %v, want %s", name, err, tc.code)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                              Form uploads                                  */
+/* -------------------------------------------------------------------------- */
+
+// PostForm POSTs values as application/x-www-form-urlencoded and decodes the
+// JSON response into v.
+func (c *BaseClient) PostForm(ctx context.Context, path string, values url.Values, v any) error {
+	hdr := http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+	return c.DoWithHeaders(ctx, http.MethodPost, path, strings.NewReader(values.Encode()), v, hdr)
+}
+
+// PostMultipart POSTs a multipart/form-data body built from fields and files
+// and decodes the JSON response into v. Each files key is used as both the form
+// field and the file name.
+func (c *BaseClient) PostMultipart(ctx context.Context, path string, fields map[string]string, files map[string]io.Reader, v any) error {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if err := writeMultipart(mw, fields, files); err != nil {
+		return ErrEncode.
+			WithError(err).
+			WithMessage("failed to build multipart body").
+			WithContext("url", c.baseURL+path)
+	}
+	hdr := http.Header{"Content-Type": {mw.FormDataContentType()}}
+	return c.DoWithHeaders(ctx, http.MethodPost, path, &buf, v, hdr)
+}
+
+func writeMultipart(mw *multipart.Writer, fields map[string]string, files map[string]io.Reader) error {
+	for k, val := range fields {
+		if err := mw.WriteField(k, val); err != nil {
+			return err
+		}
+	}
+	for name, r := range files {
+		fw, err := mw.CreateFormFile(name, name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(fw, r); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
@@ -0,0 +1,43 @@
+package httpclienttest_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/shadowofcards/go-toolkit/contexts"
+	"github.com/shadowofcards/go-toolkit/httpclient/httpclienttest"
+)
+
+func cardHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, `{"name":"ace"}`)
+}
+
+func ExampleNewTestClient() {
+	c := httpclienttest.NewTestClient(http.HandlerFunc(cardHandler))
+
+	var out struct{ Name string }
+	if err := c.Do(context.Background(), http.MethodGet, "/cards/1", nil, &out); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+	fmt.Println(out.Name)
+	// Output: ace
+}
+
+func ExampleNewRecordingClient() {
+	c, rec := httpclienttest.NewRecordingClient(http.HandlerFunc(cardHandler))
+
+	ctx := context.WithValue(context.Background(), contexts.KeyRequestID, "req-42")
+	if err := c.Do(ctx, http.MethodGet, "/cards/1", nil, nil); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(rec.Last().URL.Path)
+	fmt.Println(rec.Last().Header.Get("X-Request-Id"))
+	// Output:
+	// /cards/1
+	// req-42
+}
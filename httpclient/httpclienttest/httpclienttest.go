@@ -0,0 +1,110 @@
+// Package httpclienttest provides in-process seams for testing code built on
+// httpclient.BaseClient.
+//
+//	c, rec := httpclienttest.NewRecordingClient(handler)
+//	_ = c.Do(ctx, http.MethodGet, "/things", nil, &out)
+//	rec.Last().Header.Get("X-Request-Id")
+package httpclienttest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/shadowofcards/go-toolkit/httpclient"
+)
+
+// BaseURL is the base URL of clients built by NewTestClient.
+const BaseURL = "http://httpclienttest.local"
+
+// NewTestClient returns a BaseClient whose requests are served in-process by
+// handler, without opening a socket.
+func NewTestClient(handler http.Handler) *httpclient.BaseClient {
+	return newClient(HandlerTransport(handler), nil)
+}
+
+// NewRecordingClient is NewTestClient with every outgoing request recorded on
+// the returned transport. Extra options are applied after the transport.
+func NewRecordingClient(handler http.Handler, opts ...httpclient.Option) (*httpclient.BaseClient, *RecordingTransport) {
+	rec := &RecordingTransport{Next: HandlerTransport(handler)}
+	return newClient(rec, opts), rec
+}
+
+func newClient(rt http.RoundTripper, opts []httpclient.Option) *httpclient.BaseClient {
+	opts = append([]httpclient.Option{httpclient.WithHTTPClient(&http.Client{Transport: rt})}, opts...)
+	return httpclient.New(BaseURL, opts...)
+}
+
+// HandlerTransport is an http.RoundTripper that serves requests with an
+// http.Handler.
+func HandlerTransport(h http.Handler) http.RoundTripper {
+	return roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		res := w.Result()
+		res.Request = r
+		return res, nil
+	})
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// RecordingTransport captures every request before passing it to Next
+// (http.DefaultTransport when nil). Recorded bodies stay readable.
+type RecordingTransport struct {
+	Next http.RoundTripper
+
+	mu   sync.Mutex
+	reqs []*http.Request
+}
+
+func (t *RecordingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec := r.Clone(r.Context())
+	if r.Body != nil {
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		rec.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	t.mu.Lock()
+	t.reqs = append(t.reqs, rec)
+	t.mu.Unlock()
+
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(r)
+}
+
+// Requests returns the recorded requests in order.
+func (t *RecordingTransport) Requests() []*http.Request {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*http.Request(nil), t.reqs...)
+}
+
+// Last returns the most recent request, or nil.
+func (t *RecordingTransport) Last() *http.Request {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.reqs) == 0 {
+		return nil
+	}
+	return t.reqs[len(t.reqs)-1]
+}
+
+// Reset drops all recorded requests.
+func (t *RecordingTransport) Reset() {
+	t.mu.Lock()
+	t.reqs = nil
+	t.mu.Unlock()
+}
@@ -0,0 +1,109 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                               Shadow traffic                               */
+/* -------------------------------------------------------------------------- */
+
+// ShadowResult describes the outcome of one side of a shadowed call.
+type ShadowResult struct {
+	Status   int
+	Duration time.Duration
+	Err      error
+}
+
+type shadowCfg struct {
+	baseURL    string
+	sampleRate float64
+	compare    func(primary, shadow ShadowResult)
+}
+
+// WithShadow mirrors a sampled fraction (0..1) of calls to shadowBaseURL in the
+// background, for dark-launching a new backend. The shadow response is discarded;
+// compare, when non-nil, receives both results. Shadow failures never affect the
+// primary call.
+func WithShadow(shadowBaseURL string, sampleRate float64, compare func(primary, shadow ShadowResult)) Option {
+	return func(c *BaseClient) {
+		c.shadow = &shadowCfg{
+			baseURL:    strings.TrimRight(shadowBaseURL, "/"),
+			sampleRate: sampleRate,
+			compare:    compare,
+		}
+	}
+}
+
+func (c *BaseClient) sampleShadow() bool {
+	if c.shadow == nil || c.shadow.sampleRate <= 0 {
+		return false
+	}
+	return c.shadow.sampleRate >= 1 || rand.Float64() < c.shadow.sampleRate
+}
+
+// fireShadow replays the primary request against the shadow backend. It runs
+// detached from ctx cancellation so a finished primary call does not abort it.
+func (c *BaseClient) fireShadow(ctx context.Context, primaryReq *http.Request, path string, body []byte, primary ShadowResult) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil && c.log != nil {
+				c.log.ErrorCtx(ctx, "shadow request panicked", zap.Any("panic", r))
+			}
+		}()
+
+		if c.httpClient.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.httpClient.Timeout)
+			defer cancel()
+		}
+
+		var rd io.Reader
+		if body != nil {
+			rd = bytes.NewReader(body)
+		}
+		shadow := ShadowResult{}
+		start := time.Now()
+		req, err := http.NewRequestWithContext(ctx, primaryReq.Method, c.shadow.baseURL+path, rd)
+		if err == nil {
+			req.Header = primaryReq.Header.Clone()
+			var res *http.Response
+			res, err = c.httpClient.Do(req)
+			if res != nil {
+				shadow.Status = res.StatusCode
+				_, _ = io.Copy(io.Discard, res.Body)
+				_ = res.Body.Close()
+			}
+		}
+		shadow.Duration = time.Since(start)
+		shadow.Err = err
+
+		if c.metrics != nil {
+			tags := map[string]string{
+				"method": strings.ToUpper(primaryReq.Method),
+				"path":   path,
+				"match":  strconv.FormatBool(primary.Status == shadow.Status),
+				"status": statusCodeKey(shadow.Status),
+			}
+			c.metrics.IncWithTags(ctx, "http_client_shadow_total", 1, tags)
+			c.metrics.ObserveWithTags(ctx, "http_client_shadow_latency_diff_seconds",
+				(shadow.Duration - primary.Duration).Seconds(), tags)
+		}
+		if err != nil && c.log != nil {
+			c.log.DebugCtx(ctx, "shadow request failed", zap.String("path", path), zap.Error(err))
+		}
+		if c.shadow.compare != nil {
+			c.shadow.compare(primary, shadow)
+		}
+	}()
+}
@@ -0,0 +1,35 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                               Typed helpers                                */
+/* -------------------------------------------------------------------------- */
+
+// GetJSON issues a GET through c.Do and decodes the response into T. An empty
+// response body yields the zero value.
+func GetJSON[T any](ctx context.Context, c *BaseClient, path string) (T, error) {
+	var out T
+	err := c.Do(ctx, http.MethodGet, path, nil, allowEmpty{&out})
+	return out, err
+}
+
+// PostJSON marshals body as JSON, POSTs it through c.Do and decodes the
+// response into TResp. An empty response body yields the zero value.
+func PostJSON[TReq, TResp any](ctx context.Context, c *BaseClient, path string, body TReq) (TResp, error) {
+	var out TResp
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return out, ErrEncode.
+			WithError(err).
+			WithMessage("failed to encode JSON").
+			WithContext("url", c.baseURL+path)
+	}
+	err = c.Do(ctx, http.MethodPost, path, bytes.NewReader(raw), allowEmpty{&out})
+	return out, err
+}
@@ -0,0 +1,40 @@
+package httpclient_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/shadowofcards/go-toolkit/errors"
+	"github.com/shadowofcards/go-toolkit/httpclient"
+	"github.com/shadowofcards/go-toolkit/httpclient/httpclienttest"
+)
+
+type card struct {
+	Name string `json:"name"`
+}
+
+func emptyOK(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+func TestTypedHelpersAcceptEmptyBody(t *testing.T) {
+	c := httpclienttest.NewTestClient(http.HandlerFunc(emptyOK))
+	ctx := context.Background()
+
+	got, err := httpclient.GetJSON[card](ctx, c, "/cards/1")
+	if err != nil || got != (card{}) {
+		t.Fatalf("GetJSON = %+v, %v; want the zero value and no error", got, err)
+	}
+	posted, err := httpclient.PostJSON[card, card](ctx, c, "/cards", card{Name: "ace"})
+	if err != nil || posted != (card{}) {
+		t.Fatalf("PostJSON = %+v, %v; want the zero value and no error", posted, err)
+	}
+}
+
+func TestDoRejectsEmptyBody(t *testing.T) {
+	c := httpclienttest.NewTestClient(http.HandlerFunc(emptyOK))
+	var out card
+	err := c.Do(context.Background(), http.MethodGet, "/cards/1", nil, &out)
+	if !errors.HasCode(err, "DECODE_ERROR") {
+		t.Fatalf("Do = %v, want DECODE_ERROR", err)
+	}
+}
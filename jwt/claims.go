@@ -0,0 +1,88 @@
+package jwt
+
+import (
+	"context"
+	"time"
+
+	gjwt "github.com/golang-jwt/jwt/v5"
+)
+
+// Claims is the caller information carried by a Keycloak-style token.
+type Claims struct {
+	Subject     string
+	Issuer      string
+	Audience    []string
+	TenantID    string
+	PlayerID    string
+	Username    string
+	Roles       []string
+	Permissions []string
+	IssuedAt    time.Time
+	ExpiresAt   time.Time
+
+	// Raw holds every claim of the token.
+	Raw gjwt.MapClaims
+}
+
+// ParseOption configures Verifier.Parse.
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+	allowExpired bool
+}
+
+// AllowExpired accepts otherwise valid tokens whose exp has passed.
+func AllowExpired(allow bool) ParseOption {
+	return func(c *parseConfig) { c.allowExpired = allow }
+}
+
+// Parse validates token like Validate and returns its claims.
+func (v *Verifier) Parse(ctx context.Context, token string, opts ...ParseOption) (*Claims, error) {
+	var cfg parseConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	var raw gjwt.MapClaims
+	if err := v.Validate(ctx, token, &raw, cfg.allowExpired); err != nil {
+		return nil, err
+	}
+	return ClaimsFromMap(raw), nil
+}
+
+// ClaimsFromMap reads sub, iss, aud, tid, player_id, preferred_username,
+// realm_access.roles, perms, iat and exp from raw. Missing claims are left
+// empty.
+func ClaimsFromMap(raw gjwt.MapClaims) *Claims {
+	c := &Claims{Raw: raw}
+	c.Subject, _ = raw.GetSubject()
+	c.Issuer, _ = raw.GetIssuer()
+	c.Audience, _ = raw.GetAudience()
+	c.TenantID, _ = raw["tid"].(string)
+	c.PlayerID, _ = raw["player_id"].(string)
+	c.Username, _ = raw["preferred_username"].(string)
+	if ra, ok := raw["realm_access"].(map[string]interface{}); ok {
+		c.Roles = stringSlice(ra["roles"])
+	}
+	c.Permissions = stringSlice(raw["perms"])
+	if iat, _ := raw.GetIssuedAt(); iat != nil {
+		c.IssuedAt = iat.Time
+	}
+	if exp, _ := raw.GetExpirationTime(); exp != nil {
+		c.ExpiresAt = exp.Time
+	}
+	return c
+}
+
+func stringSlice(v interface{}) []string {
+	arr, _ := v.([]interface{})
+	out := make([]string, 0, len(arr))
+	for _, e := range arr {
+		if s, ok := e.(string); ok {
+			out = append(out, s)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
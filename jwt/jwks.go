@@ -0,0 +1,159 @@
+package jwt
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/MicahParks/jwkset"
+	"github.com/MicahParks/keyfunc/v3"
+	"golang.org/x/time/rate"
+
+	apperrors "github.com/shadowofcards/go-toolkit/errors"
+	"github.com/shadowofcards/go-toolkit/lifecycle"
+)
+
+var ErrJWKSUnavailable = apperrors.Define("JWKS_UNAVAILABLE", http.StatusServiceUnavailable, "JWK Set could not be refreshed")
+
+// jwksHealth tracks the outcome of JWKS fetches made for a Verifier.
+type jwksHealth struct {
+	mu        sync.RWMutex
+	lastOK    time.Time
+	lastErr   error
+	lastErrAt time.Time
+}
+
+func (h *jwksHealth) success() {
+	h.mu.Lock()
+	h.lastOK = time.Now()
+	h.mu.Unlock()
+}
+
+func (h *jwksHealth) failure(err error) {
+	h.mu.Lock()
+	h.lastErr, h.lastErrAt = err, time.Now()
+	h.mu.Unlock()
+}
+
+// current returns the last error unless a fetch succeeded after it.
+func (h *jwksHealth) current() (error, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.lastErr != nil && !h.lastOK.After(h.lastErrAt) {
+		return h.lastErr, false
+	}
+	return nil, !h.lastOK.IsZero()
+}
+
+// recordingTransport marks a fetch successful when the JWKS endpoint answers
+// 200; decode failures are still reported through the refresh error handler.
+type recordingTransport struct {
+	next   http.RoundTripper
+	health *jwksHealth
+}
+
+func (t recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err == nil && res.StatusCode == http.StatusOK {
+		t.health.success()
+	}
+	return res, err
+}
+
+// newRemoteKeyfunc builds a keyfunc over cfg.jwksURL that honours the HTTP
+// client, TLS and unknown-KID options and reports refresh errors to health
+// and cfg.errHandler.
+func newRemoteKeyfunc(cfg *config, health *jwksHealth) (keyfunc.Keyfunc, error) {
+	client := &http.Client{Timeout: time.Minute}
+	if cfg.httpClient != nil {
+		c := *cfg.httpClient
+		client = &c
+	}
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if cfg.skipTLSVerify {
+		if tr, ok := next.(*http.Transport); ok {
+			tr = tr.Clone()
+			if tr.TLSClientConfig == nil {
+				tr.TLSClientConfig = &tls.Config{}
+			}
+			tr.TLSClientConfig.InsecureSkipVerify = true
+			next = tr
+		}
+	}
+	client.Transport = recordingTransport{next: next, health: health}
+
+	onErr := func(ctx context.Context, err error) {
+		health.failure(err)
+		if cfg.errHandler != nil {
+			cfg.errHandler(err)
+			return
+		}
+		slog.Default().ErrorContext(ctx, "failed to refresh JWK Set", "error", err, "url", cfg.jwksURL)
+	}
+
+	store, err := jwkset.NewStorageFromHTTP(cfg.jwksURL, jwkset.HTTPClientStorageOptions{
+		Client:                    client,
+		NoErrorReturnFirstHTTPReq: true,
+		RefreshErrorHandler:       onErr,
+		RefreshInterval:           cfg.refreshInterval,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var unknownKID *rate.Limiter
+	if cfg.refreshUnknownKID {
+		unknownKID = rate.NewLimiter(rate.Every(5*time.Minute), 1)
+	}
+	storage, err := jwkset.NewHTTPClient(jwkset.HTTPClientOptions{
+		HTTPURLs:          map[string]jwkset.Storage{cfg.jwksURL: store},
+		RateLimitWaitMax:  time.Minute,
+		RefreshUnknownKID: unknownKID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keyfunc.New(keyfunc.Options{Ctx: context.Background(), Storage: storage})
+}
+
+// LastRefreshError returns the error of the most recent JWKS fetch, or nil if
+// it succeeded. It is always nil for static keys and inline JWK Sets.
+func (v *Verifier) LastRefreshError() error {
+	if v.health == nil {
+		return nil
+	}
+	err, _ := v.health.current()
+	return err
+}
+
+// Healthy reports whether the verifier holds keys from a successful fetch
+// whose latest refresh did not fail. Static keys are always healthy.
+func (v *Verifier) Healthy() bool {
+	if v.health == nil {
+		return true
+	}
+	err, fetched := v.health.current()
+	return err == nil && fetched
+}
+
+// HealthCheck wraps Healthy for lifecycle.Readiness.Handler; register it with
+// fx.Provide(lifecycle.AsHealthCheck(jwt.HealthCheck)).
+func HealthCheck(v *Verifier) lifecycle.HealthCheck {
+	return lifecycle.HealthCheck{
+		Name: "jwks",
+		Check: func(context.Context) error {
+			if v.Healthy() {
+				return nil
+			}
+			if err := v.LastRefreshError(); err != nil {
+				return ErrJWKSUnavailable.WithError(err)
+			}
+			return ErrJWKSUnavailable
+		},
+	}
+}
@@ -2,6 +2,7 @@ package jwt
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 )
@@ -18,6 +19,11 @@ type config struct {
 	refreshInterval   time.Duration
 	refreshUnknownKID bool
 	errHandler        func(error)
+	expectedIssuer    string
+	expectedAudience  string
+	algorithms        []string
+	publicKeyPEM      []byte
+	hmacSecret        []byte
 }
 
 // WithIssuer sets the issuer base URL (used to derive the default JWKS URL).
@@ -83,3 +89,52 @@ func WithErrorHandler(fn func(error)) Option {
 		return nil
 	}
 }
+
+// WithExpectedIssuer rejects tokens whose iss claim differs from iss.
+func WithExpectedIssuer(iss string) Option {
+	return func(c *config) error {
+		c.expectedIssuer = iss
+		return nil
+	}
+}
+
+// WithExpectedAudience rejects tokens whose aud claim does not contain aud.
+func WithExpectedAudience(aud string) Option {
+	return func(c *config) error {
+		c.expectedAudience = aud
+		return nil
+	}
+}
+
+// WithAllowedAlgorithms restricts the accepted signing algorithms. The default
+// is the RS and ES families (RS256/384/512, ES256/384/512).
+func WithAllowedAlgorithms(algs ...string) Option {
+	return func(c *config) error {
+		if len(algs) == 0 {
+			return errors.New("no signing algorithms allowed")
+		}
+		c.algorithms = algs
+		return nil
+	}
+}
+
+// WithPublicKeyPEM verifies tokens with a static PEM-encoded RSA or ECDSA
+// public key instead of a JWK Set; no HTTP fetch is made.
+func WithPublicKeyPEM(pem []byte) Option {
+	return func(c *config) error {
+		c.publicKeyPEM = pem
+		return nil
+	}
+}
+
+// WithHMACSecret verifies tokens with a shared secret. Unless
+// WithAllowedAlgorithms is set, only HS256/384/512 are then accepted.
+func WithHMACSecret(secret []byte) Option {
+	return func(c *config) error {
+		if len(secret) == 0 {
+			return errors.New("empty HMAC secret")
+		}
+		c.hmacSecret = secret
+		return nil
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -14,66 +15,127 @@ import (
 )
 
 var (
-	ErrConfig = apperrors.New().
-			WithHTTPStatus(http.StatusInternalServerError).
-			WithCode("JWT_CONFIG_ERROR").
-			WithMessage("JWT configuration is invalid")
-
-	ErrJWKSParse = apperrors.New().
-			WithHTTPStatus(http.StatusInternalServerError).
-			WithCode("JWKS_PARSE_ERROR").
-			WithMessage("failed to parse JWK Set")
-
-	ErrInvalidToken = apperrors.New().
-			WithHTTPStatus(http.StatusUnauthorized).
-			WithCode("INVALID_JWT").
-			WithMessage("invalid or expired token")
+	ErrConfig = apperrors.Define("JWT_CONFIG_ERROR", http.StatusInternalServerError, "JWT configuration is invalid")
+
+	ErrJWKSParse = apperrors.Define("JWKS_PARSE_ERROR", http.StatusInternalServerError, "failed to parse JWK Set")
+
+	// ErrInvalidToken shares INVALID_TOKEN with the middlewares package, so a
+	// rejected token has one code whichever layer rejects it. It was
+	// INVALID_JWT before; clients matching on the old code must be updated.
+	ErrInvalidToken = apperrors.Define("INVALID_TOKEN", http.StatusUnauthorized, "invalid or expired token")
+)
+
+// defaultAlgorithms is the allowlist used when WithAllowedAlgorithms is not set.
+var (
+	defaultAlgorithms = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+	hmacAlgorithms    = []string{"HS256", "HS384", "HS512"}
 )
 
 type Verifier struct {
-	kf keyfunc.Keyfunc
+	keyFunc    func(ctx context.Context) gjwt.Keyfunc
+	parser     []gjwt.ParserOption
+	checked    []claimCheck
+	algorithms []string
+	health     *jwksHealth
+}
+
+// claimCheck maps a claim validation error to the claim and expected value
+// reported in the INVALID_TOKEN context.
+type claimCheck struct {
+	err      error
+	claim    string
+	expected string
 }
 
 func New(opts ...Option) (*Verifier, error) {
-	cfg := &config{refreshInterval: time.Hour}
+	cfg := &config{refreshInterval: time.Hour, refreshUnknownKID: true}
 	for _, o := range opts {
 		if err := o(cfg); err != nil {
 			return nil, ErrConfig.WithError(err)
 		}
 	}
 
-	var kf keyfunc.Keyfunc
-	var err error
-
-	if len(cfg.jwksJSON) > 0 {
+	var health *jwksHealth
+	if cfg.hmacSecret == nil && len(cfg.publicKeyPEM) == 0 && len(cfg.jwksJSON) == 0 {
+		health = &jwksHealth{}
+	}
+	keyFunc, err := newKeyFunc(cfg, health)
+	if err != nil {
+		return nil, err
+	}
 
-		kf, err = keyfunc.NewJWKSetJSON(cfg.jwksJSON)
-		if err != nil {
-			return nil, ErrJWKSParse.WithError(err)
+	if len(cfg.algorithms) == 0 {
+		cfg.algorithms = defaultAlgorithms
+		if cfg.hmacSecret != nil {
+			cfg.algorithms = hmacAlgorithms
 		}
-	} else {
+	}
+	v := &Verifier{
+		keyFunc:    keyFunc,
+		parser:     []gjwt.ParserOption{gjwt.WithValidMethods(cfg.algorithms)},
+		algorithms: cfg.algorithms,
+		health:     health,
+	}
+	if cfg.expectedIssuer != "" {
+		v.parser = append(v.parser, gjwt.WithIssuer(cfg.expectedIssuer))
+		v.checked = append(v.checked, claimCheck{gjwt.ErrTokenInvalidIssuer, "iss", cfg.expectedIssuer})
+	}
+	if cfg.expectedAudience != "" {
+		v.parser = append(v.parser, gjwt.WithAudience(cfg.expectedAudience))
+		v.checked = append(v.checked, claimCheck{gjwt.ErrTokenInvalidAudience, "aud", cfg.expectedAudience})
+	}
+	return v, nil
+}
 
-		if cfg.jwksURL == "" && cfg.issuer == "" {
-			return nil, ErrConfig
-		}
-		if cfg.jwksURL == "" {
-			iss := strings.TrimRight(cfg.issuer, "/")
-			cfg.jwksURL = iss + "/protocol/openid-connect/certs"
+// newKeyFunc picks the key source: a static key, an inline JWK Set, or a JWKS
+// endpoint refreshed in the background.
+func newKeyFunc(cfg *config, health *jwksHealth) (func(ctx context.Context) gjwt.Keyfunc, error) {
+	switch {
+	case cfg.hmacSecret != nil:
+		return staticKey(cfg.hmacSecret), nil
+	case len(cfg.publicKeyPEM) > 0:
+		key, err := parsePublicKeyPEM(cfg.publicKeyPEM)
+		if err != nil {
+			return nil, ErrConfig.WithError(err)
 		}
-
-		kf, err = keyfunc.NewDefaultOverrideCtx(
-			context.Background(),
-			[]string{cfg.jwksURL},
-			keyfunc.Override{
-				RefreshInterval: cfg.refreshInterval,
-			},
-		)
+		return staticKey(key), nil
+	case len(cfg.jwksJSON) > 0:
+		kf, err := keyfunc.NewJWKSetJSON(cfg.jwksJSON)
 		if err != nil {
 			return nil, ErrJWKSParse.WithError(err)
 		}
+		return kf.KeyfuncCtx, nil
+	}
+
+	if cfg.jwksURL == "" && cfg.issuer == "" {
+		return nil, ErrConfig
+	}
+	if cfg.jwksURL == "" {
+		iss := strings.TrimRight(cfg.issuer, "/")
+		cfg.jwksURL = iss + "/protocol/openid-connect/certs"
+	}
+
+	kf, err := newRemoteKeyfunc(cfg, health)
+	if err != nil {
+		return nil, ErrJWKSParse.WithError(err)
 	}
+	return kf.KeyfuncCtx, nil
+}
 
-	return &Verifier{kf: kf}, nil
+func staticKey(key any) func(ctx context.Context) gjwt.Keyfunc {
+	kf := func(*gjwt.Token) (any, error) { return key, nil }
+	return func(context.Context) gjwt.Keyfunc { return kf }
+}
+
+func parsePublicKeyPEM(data []byte) (any, error) {
+	if key, err := gjwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	key, err := gjwt.ParseECPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, errors.New("PEM is not an RSA or ECDSA public key")
+	}
+	return key, nil
 }
 
 func (v *Verifier) Validate(
@@ -85,9 +147,24 @@ func (v *Verifier) Validate(
 	token, err := gjwt.ParseWithClaims(
 		tokenString,
 		claims,
-		v.kf.KeyfuncCtx(ctx),
+		v.keyFunc(ctx),
+		v.parser...,
 	)
 	if err != nil {
+		if token != nil {
+			if alg, ok := token.Header["alg"].(string); ok && !slices.Contains(v.algorithms, alg) {
+				return ErrInvalidToken.WithError(err).WithContext("alg", alg)
+			}
+		}
+		// Claim errors are joined, so an expired token may also carry an
+		// issuer or audience mismatch that must still be reported.
+		for _, c := range v.checked {
+			if errors.Is(err, c.err) {
+				return ErrInvalidToken.WithError(err).
+					WithContext("claim", c.claim).
+					WithContext("expected", c.expected)
+			}
+		}
 		if allowExpired && errors.Is(err, gjwt.ErrTokenExpired) {
 			return nil
 		}
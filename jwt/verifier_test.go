@@ -0,0 +1,143 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	gjwt "github.com/golang-jwt/jwt/v5"
+
+	apperrors "github.com/shadowofcards/go-toolkit/errors"
+	"github.com/shadowofcards/go-toolkit/jwt"
+)
+
+const (
+	testIssuer   = "https://auth.example.com"
+	testAudience = "game-api"
+)
+
+// rsaKey returns a fresh RSA key and its public half as PEM.
+func rsaKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func sign(t *testing.T, method gjwt.SigningMethod, key any, claims gjwt.MapClaims) string {
+	t.Helper()
+	s, err := gjwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validClaims() gjwt.MapClaims {
+	return gjwt.MapClaims{
+		"sub": "player-1",
+		"iss": testIssuer,
+		"aud": testAudience,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func newVerifier(t *testing.T, opts ...jwt.Option) *jwt.Verifier {
+	t.Helper()
+	v, err := jwt.New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func validate(v *jwt.Verifier, token string, allowExpired bool) error {
+	return v.Validate(context.Background(), token, gjwt.MapClaims{}, allowExpired)
+}
+
+// invalid asserts err is INVALID_TOKEN carrying the given context.
+func invalid(t *testing.T, err error, want map[string]string) {
+	t.Helper()
+	ae, ok := apperrors.FromError(err)
+	if !ok || ae.Code != "INVALID_TOKEN" {
+		t.Fatalf("err = %v, want INVALID_TOKEN", err)
+	}
+	for k, v := range want {
+		if got := ae.Context[k]; got != v {
+			t.Errorf("context %s = %v, want %q", k, got, v)
+		}
+	}
+}
+
+func TestPEMKeyAcceptsRS256(t *testing.T) {
+	key, pub := rsaKey(t)
+	v := newVerifier(t, jwt.WithPublicKeyPEM(pub),
+		jwt.WithExpectedIssuer(testIssuer), jwt.WithExpectedAudience(testAudience))
+
+	if err := validate(v, sign(t, gjwt.SigningMethodRS256, key, validClaims()), false); err != nil {
+		t.Fatalf("Validate = %v", err)
+	}
+}
+
+func TestHMACSecretAcceptsHS256(t *testing.T) {
+	secret := []byte("shared-secret")
+	v := newVerifier(t, jwt.WithHMACSecret(secret))
+
+	if err := validate(v, sign(t, gjwt.SigningMethodHS256, secret, validClaims()), false); err != nil {
+		t.Fatalf("Validate = %v", err)
+	}
+}
+
+func TestDefaultAllowlistRejectsHS256(t *testing.T) {
+	_, pub := rsaKey(t)
+	v := newVerifier(t, jwt.WithPublicKeyPEM(pub))
+
+	token := sign(t, gjwt.SigningMethodHS256, pub, validClaims())
+	invalid(t, validate(v, token, false), map[string]string{"alg": "HS256"})
+}
+
+func TestClaimMismatch(t *testing.T) {
+	key, pub := rsaKey(t)
+	v := newVerifier(t, jwt.WithPublicKeyPEM(pub),
+		jwt.WithExpectedIssuer(testIssuer), jwt.WithExpectedAudience(testAudience))
+
+	tests := []struct {
+		claim, value, expected string
+	}{
+		{"iss", "https://evil.example.com", testIssuer},
+		{"aud", "other-api", testAudience},
+	}
+	for _, tt := range tests {
+		t.Run(tt.claim, func(t *testing.T) {
+			claims := validClaims()
+			claims[tt.claim] = tt.value
+			err := validate(v, sign(t, gjwt.SigningMethodRS256, key, claims), false)
+			invalid(t, err, map[string]string{"claim": tt.claim, "expected": tt.expected})
+		})
+	}
+}
+
+func TestAllowExpiredStillChecksIssuer(t *testing.T) {
+	key, pub := rsaKey(t)
+	v := newVerifier(t, jwt.WithPublicKeyPEM(pub), jwt.WithExpectedIssuer(testIssuer))
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	if err := validate(v, sign(t, gjwt.SigningMethodRS256, key, expired), true); err != nil {
+		t.Fatalf("expired token with allowExpired: Validate = %v", err)
+	}
+
+	expired["iss"] = "https://evil.example.com"
+	err := validate(v, sign(t, gjwt.SigningMethodRS256, key, expired), true)
+	invalid(t, err, map[string]string{"claim": "iss", "expected": testIssuer})
+}
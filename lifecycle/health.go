@@ -0,0 +1,51 @@
+package lifecycle
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/fx"
+)
+
+// HealthCheck is a named dependency probe used by Readiness.Handler.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthChecks collects every HealthCheck registered with AsHealthCheck.
+type HealthChecks struct {
+	fx.In
+	Checks []HealthCheck `group:"health_checks"`
+}
+
+// AsHealthCheck annotates a constructor returning a HealthCheck so it joins the
+// "health_checks" group, e.g. fx.Provide(lifecycle.AsHealthCheck(messaging.HealthCheck)).
+func AsHealthCheck(constructor any) any {
+	return fx.Annotate(constructor, fx.ResultTags(`group:"health_checks"`))
+}
+
+// defaultCheckTimeout bounds a probe when the request has no deadline.
+const defaultCheckTimeout = 2 * time.Second
+
+// runChecks returns the errors of the failing checks by name.
+func runChecks(ctx context.Context, checks []HealthCheck) map[string]error {
+	if len(checks) == 0 {
+		return nil
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultCheckTimeout)
+		defer cancel()
+	}
+	var failed map[string]error
+	for _, c := range checks {
+		if err := c.Check(ctx); err != nil {
+			if failed == nil {
+				failed = map[string]error{}
+			}
+			failed[c.Name] = err
+		}
+	}
+	return failed
+}
@@ -0,0 +1,95 @@
+package lifecycle
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/shadowofcards/go-toolkit/logging"
+)
+
+// Readiness is a process-wide "accepting new work" flag. It is flipped to false
+// as soon as shutdown starts so load balancers stop routing traffic while
+// websocket connections and NATS subscriptions drain.
+type Readiness struct {
+	ready atomic.Bool
+	log   *logging.Logger
+}
+
+type ReadinessOption func(*Readiness)
+
+// WithReadinessLogger logs why health checks fail; the response itself only
+// names the failing checks.
+func WithReadinessLogger(log *logging.Logger) ReadinessOption {
+	return func(r *Readiness) {
+		r.log = log
+	}
+}
+
+// NewReadiness returns a Readiness that starts ready.
+func NewReadiness(opts ...ReadinessOption) *Readiness {
+	r := &Readiness{}
+	for _, o := range opts {
+		o(r)
+	}
+	r.ready.Store(true)
+	return r
+}
+
+func (r *Readiness) Ready() bool      { return r.ready.Load() }
+func (r *Readiness) SetReady(ok bool) { r.ready.Store(ok) }
+
+// Handler answers 200 {"status":"ready"} or 503 with status "draining" while
+// shutting down, or "unhealthy" plus the names of the failing checks when any
+// of checks fails. Check errors can carry hostnames and URLs, so they are only
+// logged. Mount it on the readiness probe path.
+func (r *Readiness) Handler(checks ...HealthCheck) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if !r.Ready() {
+			return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"status": "draining"})
+		}
+		if failed := runChecks(c.Context(), checks); len(failed) > 0 {
+			names := make([]string, 0, len(failed))
+			for name, err := range failed {
+				names = append(names, name)
+				if r.log != nil {
+					r.log.WarnCtx(c.Context(), "health check failed", zap.String("check", name), zap.Error(err))
+				}
+			}
+			sort.Strings(names)
+			return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"status": "unhealthy", "checks": names})
+		}
+		return c.JSON(fiber.Map{"status": "ready"})
+	}
+}
+
+type readinessParams struct {
+	fx.In
+	Log *logging.Logger `optional:"true"`
+}
+
+func provideReadiness(p readinessParams) *Readiness {
+	return NewReadiness(WithReadinessLogger(p.Log))
+}
+
+// Module provides a shared *Readiness and flips it to false in OnStop. Modules
+// that drain (e.g. messaging.ProvideConn) also flip it before draining, since
+// fx does not guarantee this hook runs first.
+func Module() fx.Option {
+	return fx.Options(
+		fx.Provide(provideReadiness),
+		fx.Invoke(func(lc fx.Lifecycle, r *Readiness) {
+			lc.Append(fx.Hook{
+				OnStop: func(context.Context) error {
+					r.SetReady(false)
+					return nil
+				},
+			})
+		}),
+	)
+}
@@ -0,0 +1,48 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/shadowofcards/go-toolkit/logging"
+)
+
+func TestReadinessHidesCheckErrors(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	r := NewReadiness(WithReadinessLogger(&logging.Logger{Logger: zap.New(core)}))
+	checks := []HealthCheck{
+		{Name: "nats", Check: func(context.Context) error {
+			return errors.New("dial tcp nats-0.internal:4222: connection refused")
+		}},
+		{Name: "jwks", Check: func(context.Context) error { return nil }},
+	}
+
+	app := fiber.New()
+	app.Get("/ready", r.Handler(checks...))
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", res.StatusCode)
+	}
+	if want := `{"checks":["nats"],"status":"unhealthy"}`; string(body) != want {
+		t.Fatalf("body = %s, want %s", body, want)
+	}
+	entries := logs.FilterMessage("health check failed").All()
+	if len(entries) != 1 || !strings.Contains(entries[0].ContextMap()["error"].(string), "nats-0.internal") {
+		t.Fatalf("logged %v, want the nats error", entries)
+	}
+}
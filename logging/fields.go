@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/shadowofcards/go-toolkit/contexts"
+	"go.uber.org/zap"
+)
+
+type contextField struct {
+	key  any
+	name string
+}
+
+// fieldNames are the log field names of the well-known contexts keys; other
+// keys are logged under their string form.
+var fieldNames = map[any]string{
+	contexts.KeyRequestID: "request-id",
+	contexts.KeyTenantID:  "tenant-id",
+	contexts.KeyUserID:    "user-id",
+	contexts.KeyOrigin:    "origin",
+	contexts.KeyPlayerID:  "player-id",
+	contexts.KeyCallerID:  "caller-id",
+	contexts.KeyRegion:    "region",
+}
+
+var ctxFields atomic.Pointer[[]contextField]
+
+func init() {
+	SetContextFields(contexts.KeyRequestID, contexts.KeyTenantID, contexts.KeyUserID, contexts.KeyOrigin)
+}
+
+// SetContextFields sets the contexts keys whose values the *Ctx methods attach
+// to every entry (default: request id, tenant id, user id and origin). Call it
+// with no keys to log none.
+func SetContextFields(keys ...any) {
+	fs := make([]contextField, len(keys))
+	for i, k := range keys {
+		name, ok := fieldNames[k]
+		if !ok {
+			name = fmt.Sprint(k)
+		}
+		fs[i] = contextField{key: k, name: name}
+	}
+	ctxFields.Store(&fs)
+}
+
+func contextFields(ctx context.Context) []zap.Field {
+	var out []zap.Field
+	for _, f := range *ctxFields.Load() {
+		switch v := ctx.Value(f.key).(type) {
+		case nil:
+		case string:
+			if v != "" {
+				out = append(out, zap.String(f.name, v))
+			}
+		default:
+			out = append(out, zap.Any(f.name, v))
+		}
+	}
+	return out
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"strings"
 
-	"github.com/shadowofcards/go-toolkit/contexts"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -34,6 +33,22 @@ func WithLevel(level string) Option {
 	}
 }
 
+// WithSampling caps repeated entries: within each second, the first initial
+// entries with the same level and message are logged, then only every
+// thereafter-th one. The production default is 100/100.
+func WithSampling(initial, thereafter int) Option {
+	return func(cfg *zap.Config) {
+		cfg.Sampling = &zap.SamplingConfig{Initial: initial, Thereafter: thereafter}
+	}
+}
+
+// WithoutSampling logs every entry.
+func WithoutSampling() Option {
+	return func(cfg *zap.Config) {
+		cfg.Sampling = nil
+	}
+}
+
 func WithDevelopmentEncoder() Option {
 	return func(cfg *zap.Config) {
 		dev := zap.NewDevelopmentConfig()
@@ -57,8 +72,8 @@ func toLevel(lvl string) zapcore.Level {
 }
 
 func (l *Logger) with(ctx context.Context) *Logger {
-	if v, ok := ctx.Value(contexts.KeyRequestID).(string); ok && v != "" {
-		return &Logger{l.Logger.With(zap.String("request-id", v))}
+	if fs := contextFields(ctx); len(fs) > 0 {
+		return &Logger{l.Logger.With(fs...)}
 	}
 	return l
 }
@@ -75,3 +90,14 @@ func (l *Logger) WarnCtx(ctx context.Context, msg string, f ...zap.Field) {
 func (l *Logger) ErrorCtx(ctx context.Context, msg string, f ...zap.Field) {
 	l.with(ctx).Error(msg, f...)
 }
+
+// Named returns a sub-logger whose entries carry name, keeping the *Ctx helpers.
+func (l *Logger) Named(name string) *Logger {
+	return &Logger{l.Logger.Named(name)}
+}
+
+// With returns a sub-logger that adds fields to every entry, keeping the *Ctx
+// helpers.
+func (l *Logger) With(fields ...zap.Field) *Logger {
+	return &Logger{l.Logger.With(fields...)}
+}
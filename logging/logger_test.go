@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shadowofcards/go-toolkit/contexts"
+)
+
+// fileLogger returns a logger writing JSON to a temp file and a function
+// returning the decoded entries written so far.
+func fileLogger(t *testing.T, opts ...Option) (*Logger, func() []map[string]any) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "log.json")
+	l, err := New(append([]Option{WithOutputPaths(path)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, func() []map[string]any {
+		_ = l.Sync()
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		var out []map[string]any
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			var e map[string]any
+			if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+				t.Fatalf("invalid log line %q: %v", sc.Text(), err)
+			}
+			out = append(out, e)
+		}
+		return out
+	}
+}
+
+func TestSamplingDropsRepeatedEntries(t *testing.T) {
+	const initial, thereafter, loop = 10, 100, 1000
+
+	l, entries := fileLogger(t, WithSampling(initial, thereafter))
+	for i := 0; i < loop; i++ {
+		l.Error("flood")
+	}
+	// The sampler's counters reset every second; allow the loop to straddle
+	// one reset, which lets up to initial more entries through.
+	got := len(entries())
+	if max := 2*initial + loop/thereafter; got == 0 || got > max {
+		t.Fatalf("logged %d of %d entries, want between 1 and %d", got, loop, max)
+	}
+}
+
+func TestWithoutSamplingLogsEverything(t *testing.T) {
+	const loop = 300
+
+	l, entries := fileLogger(t, WithoutSampling())
+	for i := 0; i < loop; i++ {
+		l.Error("flood")
+	}
+	if got := len(entries()); got != loop {
+		t.Fatalf("logged %d entries, want %d", got, loop)
+	}
+}
+
+func TestInfoCtxAddsRequestID(t *testing.T) {
+	l, entries := fileLogger(t)
+	ctx := context.WithValue(context.Background(), contexts.KeyRequestID, "req-42")
+	l.InfoCtx(ctx, "hello")
+
+	got := entries()
+	if len(got) != 1 {
+		t.Fatalf("logged %d entries, want 1", len(got))
+	}
+	if rid := got[0]["request-id"]; rid != "req-42" {
+		t.Fatalf("request-id = %v, want req-42 (entry %v)", rid, got[0])
+	}
+}
@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const rotationScheme = "lumberjack"
+
+var registerRotation sync.Once
+
+// WithOutputPaths sets where entries are written (zap paths or URLs such as
+// "stderr" or "/var/log/app.log"), replacing the default stderr.
+func WithOutputPaths(paths ...string) Option {
+	return func(cfg *zap.Config) {
+		cfg.OutputPaths = paths
+	}
+}
+
+// WithRotation writes entries to filename, rotating it once it reaches
+// maxSizeMB and keeping at most maxBackups old files for maxAgeDays (0 keeps
+// all). It replaces the configured output paths.
+func WithRotation(filename string, maxSizeMB, maxBackups, maxAgeDays int) Option {
+	return func(cfg *zap.Config) {
+		registerRotation.Do(func() {
+			_ = zap.RegisterSink(rotationScheme, newRotationSink)
+		})
+		if abs, err := filepath.Abs(filename); err == nil {
+			filename = abs
+		}
+		q := url.Values{}
+		q.Set("maxsize", strconv.Itoa(maxSizeMB))
+		q.Set("maxbackups", strconv.Itoa(maxBackups))
+		q.Set("maxage", strconv.Itoa(maxAgeDays))
+		u := url.URL{Scheme: rotationScheme, Path: filepath.ToSlash(filename), RawQuery: q.Encode()}
+		cfg.OutputPaths = []string{u.String()}
+	}
+}
+
+type rotationSink struct{ *lumberjack.Logger }
+
+func (rotationSink) Sync() error { return nil }
+
+func newRotationSink(u *url.URL) (zap.Sink, error) {
+	q := u.Query()
+	atoi := func(k string) int {
+		n, _ := strconv.Atoi(q.Get(k))
+		return n
+	}
+	return rotationSink{&lumberjack.Logger{
+		Filename:   filepath.FromSlash(u.Path),
+		MaxSize:    atoi("maxsize"),
+		MaxBackups: atoi("maxbackups"),
+		MaxAge:     atoi("maxage"),
+	}}, nil
+}
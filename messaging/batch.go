@@ -0,0 +1,127 @@
+package messaging
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/xid"
+	apperrors "github.com/shadowofcards/go-toolkit/errors"
+	"go.uber.org/zap"
+)
+
+// ErrBatchPublish reports a partially failed PublishBatch; its context carries
+// failed_indices ([]int) and total.
+var ErrBatchPublish = apperrors.Define("BATCH_PUBLISH_FAILED", http.StatusBadGateway, "some messages of the batch were not published")
+
+// PublishBatch publishes msgs on subject in one go: JetStream messages are sent
+// with PublishMsgAsync and awaited together, core messages share a single
+// flush. Per-message failures are collected into ErrBatchPublish instead of
+// aborting the batch; a failed flush marks every core message as failed.
+func (p *Publisher) PublishBatch(ctx context.Context, subject string, msgs []any) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	if p.prefix != "" {
+		subject = p.prefix + subject
+	}
+	if err := validateSubject(subject, false); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	var failed []int
+	var lastErr error
+	fail := func(i int, err error) {
+		failed = append(failed, i)
+		lastErr = err
+	}
+
+	if p.useJetStream && p.js != nil {
+		if err := p.EnsureStream(subject); err != nil {
+			return err
+		}
+		futures := make(map[int]nats.PubAckFuture, len(msgs))
+		for i, msg := range msgs {
+			hdr := headersFromContext(ctx, p.headerFields, nats.Header{"Nats-Msg-Id": []string{xid.New().String()}})
+			data, hdr, err := p.encode(ctx, subject, msg, hdr)
+			if err != nil {
+				fail(i, err)
+				continue
+			}
+			f, err := p.js.PublishMsgAsync(&nats.Msg{Subject: subject, Data: data, Header: hdr})
+			if err != nil {
+				fail(i, err)
+				continue
+			}
+			futures[i] = f
+		}
+		select {
+		case <-p.js.PublishAsyncComplete():
+		case <-ctx.Done():
+		}
+		for i := range msgs {
+			f, ok := futures[i]
+			if !ok {
+				continue
+			}
+			select {
+			case <-f.Ok():
+			case err := <-f.Err():
+				fail(i, err)
+			case <-ctx.Done():
+				fail(i, ctx.Err())
+			}
+		}
+	} else {
+		for i, msg := range msgs {
+			data, hdr, err := p.encode(ctx, subject, msg, headersFromContext(ctx, p.headerFields, nats.Header{}))
+			if err == nil {
+				err = p.conn.PublishMsg(&nats.Msg{Subject: subject, Data: data, Header: hdr})
+			}
+			if err != nil {
+				fail(i, err)
+			}
+		}
+		var err error
+		if dl, ok := ctx.Deadline(); ok {
+			err = p.conn.FlushTimeout(time.Until(dl))
+		} else {
+			err = p.conn.Flush()
+		}
+		if err != nil {
+			// Nothing tells which buffered messages reached the server.
+			failed = failed[:0]
+			for i := range msgs {
+				fail(i, err)
+			}
+		}
+	}
+
+	status := "success"
+	if len(failed) > 0 {
+		status = "partial_failure"
+	}
+	if p.metrics != nil {
+		tags := map[string]string{"subject": subject, "status": status}
+		p.metrics.IncWithTags(ctx, "nats_publish_batch_total", int64(len(msgs)), tags)
+		p.metrics.ObserveWithTags(ctx, "nats_publish_duration_seconds", time.Since(start).Seconds(),
+			map[string]string{"subject": subject, "status": status, "batch": "true"})
+	}
+	if len(failed) > 0 {
+		p.log.ErrorCtx(ctx, "batch publish partially failed",
+			zap.String("subject", subject),
+			zap.Int("failed", len(failed)),
+			zap.Int("total", len(msgs)),
+			zap.Error(lastErr),
+		)
+		return ErrBatchPublish.
+			WithError(lastErr).
+			WithContext("subject", subject).
+			WithContext("failed_indices", failed).
+			WithContext("total", len(msgs))
+	}
+	p.log.DebugCtx(ctx, "batch published", zap.String("subject", subject), zap.Int("size", len(msgs)))
+	return nil
+}
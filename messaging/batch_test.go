@@ -0,0 +1,46 @@
+package messaging
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+
+	apperrors "github.com/shadowofcards/go-toolkit/errors"
+	"github.com/shadowofcards/go-toolkit/metrics"
+)
+
+// countingRecorder keeps the IncWithTags deltas by status tag.
+type countingRecorder struct {
+	metrics.Recorder
+	incs map[string]int64
+}
+
+func (r *countingRecorder) IncWithTags(_ context.Context, name string, delta int64, tags map[string]string) error {
+	r.incs[name+"/"+tags["status"]] += delta
+	return nil
+}
+
+func TestPublishBatchFlushFailureReportsEveryIndex(t *testing.T) {
+	rec := &countingRecorder{Recorder: metrics.NoOp(), incs: map[string]int64{}}
+	// A closed conn fails both the publishes and the flush.
+	nc, err := nats.Connect("nats://127.0.0.1:1", nats.RetryOnFailedConnect(true), nats.MaxReconnects(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nc.Close()
+	p := NewPublisher(nc, testLogger(), WithMetrics(rec))
+
+	err = p.PublishBatch(context.Background(), "events.batch", []any{1, 2, 3})
+	ae, ok := apperrors.FromError(err)
+	if !ok || ae.Code != ErrBatchPublish.Code {
+		t.Fatalf("PublishBatch = %v, want %s", err, ErrBatchPublish.Code)
+	}
+	if got, _ := ae.Context["failed_indices"].([]int); !slices.Equal(got, []int{0, 1, 2}) {
+		t.Errorf("failed_indices = %v, want [0 1 2]", ae.Context["failed_indices"])
+	}
+	if got := rec.incs["nats_publish_batch_total/partial_failure"]; got != 3 {
+		t.Errorf("nats_publish_batch_total = %d, want 3", got)
+	}
+}
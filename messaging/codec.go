@@ -0,0 +1,146 @@
+package messaging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	contentTypeHeader     = "Content-Type"
+	contentEncodingHeader = "X-Content-Encoding"
+	encodingGzip          = "gzip"
+)
+
+// Codec serializes message payloads. The content type travels in the
+// Content-Type header so subscribers can pick the matching codec.
+type Codec interface {
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	JSONCodec    Codec = jsonCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string                { return "application/json" }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string                { return "application/msgpack" }
+func (msgpackCodec) Marshal(v any) ([]byte, error)      { return msgpack.Marshal(v) }
+func (msgpackCodec) Unmarshal(data []byte, v any) error { return msgpack.Unmarshal(data, v) }
+
+// codecFor resolves the codec announced by a message, falling back to def for
+// producers that do not set Content-Type (e.g. JSON-only publishers).
+func codecFor(contentType string, def Codec) Codec {
+	switch {
+	case strings.HasPrefix(contentType, MsgpackCodec.ContentType()):
+		return MsgpackCodec
+	case strings.HasPrefix(contentType, JSONCodec.ContentType()):
+		return JSONCodec
+	case def != nil:
+		return def
+	default:
+		return JSONCodec
+	}
+}
+
+type ctxKeyCodec struct{}
+
+// Unmarshal decodes a handler payload with the codec of the message being
+// handled (its Content-Type header, or the subscriber's codec), JSON by default.
+func Unmarshal(ctx context.Context, data []byte, v any) error {
+	c, ok := ctx.Value(ctxKeyCodec{}).(Codec)
+	if !ok {
+		c = JSONCodec
+	}
+	return c.Unmarshal(data, v)
+}
+
+// encode marshals msg with the publisher codec, gzips it when it reaches the
+// configured threshold and records raw vs wire sizes.
+func (p *Publisher) encode(ctx context.Context, subject string, msg any, hdr nats.Header) ([]byte, nats.Header, error) {
+	data, err := p.codec.Marshal(msg)
+	if err != nil {
+		return nil, hdr, err
+	}
+	raw := len(data)
+	hdr.Set(contentTypeHeader, p.codec.ContentType())
+	if p.gzip && raw >= p.gzipMin {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, hdr, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, hdr, err
+		}
+		data = buf.Bytes()
+		hdr.Set(contentEncodingHeader, encodingGzip)
+	}
+	if p.metrics != nil {
+		tags := map[string]string{
+			"subject":    subject,
+			"compressed": strconv.FormatBool(hdr.Get(contentEncodingHeader) == encodingGzip),
+		}
+		p.metrics.ObserveWithTags(ctx, "nats_publish_payload_bytes", float64(raw), mergeTags(tags, map[string]string{"size": "raw"}))
+		p.metrics.ObserveWithTags(ctx, "nats_publish_payload_bytes", float64(len(data)), mergeTags(tags, map[string]string{"size": "wire"}))
+	}
+	return data, hdr, nil
+}
+
+// defaultMaxInflated bounds a decompressed payload when neither an option nor
+// the connection gives a limit; it is the NATS server's default max_payload.
+const defaultMaxInflated = 1 << 20
+
+// inflateLimit returns max when set, else the connection's max payload.
+func inflateLimit(nc *nats.Conn, max int64) int64 {
+	if max > 0 {
+		return max
+	}
+	if nc != nil {
+		if n := nc.MaxPayload(); n > 0 {
+			return n
+		}
+	}
+	return defaultMaxInflated
+}
+
+// payload returns the message body, transparently gunzipping it when the
+// producer set X-Content-Encoding: gzip. A body that is not valid gzip or
+// inflates past limit bytes fails with ErrDecodeMessage.
+func payload(m *nats.Msg, limit int64) ([]byte, error) {
+	if m.Header.Get(contentEncodingHeader) != encodingGzip {
+		return m.Data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(m.Data))
+	if err != nil {
+		return nil, ErrDecodeMessage.WithError(err).WithContext("subject", m.Subject)
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(io.LimitReader(zr, limit+1))
+	if err != nil {
+		return nil, ErrDecodeMessage.WithError(err).WithContext("subject", m.Subject)
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrDecodeMessage.
+			WithMessage("decompressed payload exceeds the size limit").
+			WithContext("subject", m.Subject).
+			WithContext("limit", limit)
+	}
+	return data, nil
+}
@@ -0,0 +1,61 @@
+package messaging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+
+	apperrors "github.com/shadowofcards/go-toolkit/errors"
+)
+
+func gzipMsg(t *testing.T, data []byte) *nats.Msg {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	m := nats.NewMsg("events.test")
+	m.Data = buf.Bytes()
+	m.Header.Set(contentEncodingHeader, encodingGzip)
+	return m
+}
+
+func TestPayloadBoundsDecompression(t *testing.T) {
+	raw := bytes.Repeat([]byte("a"), 4096)
+	m := gzipMsg(t, raw)
+
+	data, err := payload(m, int64(len(raw)))
+	if err != nil || !bytes.Equal(data, raw) {
+		t.Fatalf("payload at the limit = %d bytes, %v", len(data), err)
+	}
+
+	_, err = payload(m, int64(len(raw)-1))
+	if !apperrors.HasCode(err, ErrDecodeMessage.Code) {
+		t.Fatalf("payload over the limit = %v, want %s", err, ErrDecodeMessage.Code)
+	}
+}
+
+func TestPayloadRejectsInvalidGzip(t *testing.T) {
+	m := nats.NewMsg("events.test")
+	m.Data = []byte("not gzip")
+	m.Header.Set(contentEncodingHeader, encodingGzip)
+
+	if _, err := payload(m, defaultMaxInflated); !apperrors.HasCode(err, ErrDecodeMessage.Code) {
+		t.Fatalf("payload = %v, want %s", err, ErrDecodeMessage.Code)
+	}
+}
+
+func TestInflateLimit(t *testing.T) {
+	if got := inflateLimit(&nats.Conn{}, 0); got != defaultMaxInflated {
+		t.Errorf("unconnected default = %d, want %d", got, defaultMaxInflated)
+	}
+	if got := inflateLimit(&nats.Conn{}, 512); got != 512 {
+		t.Errorf("configured limit = %d, want 512", got)
+	}
+}
@@ -0,0 +1,62 @@
+package messaging
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// defaultDedupSize caps the number of message ids SubWithDedup remembers.
+const defaultDedupSize = 10000
+
+type dedupEntry struct {
+	id      string
+	expires time.Time
+}
+
+// dedupCache is a size-bounded LRU of processed message ids with a TTL.
+type dedupCache struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	max   int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+func newDedupCache(ttl time.Duration, max int) *dedupCache {
+	return &dedupCache{ttl: ttl, max: max, ll: list.New(), items: map[string]*list.Element{}}
+}
+
+// seen reports whether id was marked within the window.
+func (d *dedupCache) seen(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	el, ok := d.items[id]
+	if !ok {
+		return false
+	}
+	if time.Now().After(el.Value.(*dedupEntry).expires) {
+		d.ll.Remove(el)
+		delete(d.items, id)
+		return false
+	}
+	return true
+}
+
+// mark records id as processed, evicting the oldest ids beyond max.
+func (d *dedupCache) mark(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	exp := time.Now().Add(d.ttl)
+	if el, ok := d.items[id]; ok {
+		el.Value.(*dedupEntry).expires = exp
+		d.ll.MoveToFront(el)
+		return
+	}
+	d.items[id] = d.ll.PushFront(&dedupEntry{id: id, expires: exp})
+	for d.ll.Len() > d.max {
+		old := d.ll.Back()
+		d.ll.Remove(old)
+		delete(d.items, old.Value.(*dedupEntry).id)
+	}
+}
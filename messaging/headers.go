@@ -0,0 +1,73 @@
+package messaging
+
+import (
+	"context"
+	"sort"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/shadowofcards/go-toolkit/contexts"
+)
+
+// HeaderField binds a context key (one of the contexts.Key* values) to the NATS
+// header that carries it across the publish/consume boundary.
+type HeaderField struct {
+	Header string
+	Key    any
+}
+
+// DefaultHeaderFields are forwarded unless overridden with WithForwardedHeaders
+// / SubWithForwardedHeaders. The header names match the ones httpclient sends.
+// They deliberately avoid a Nats- prefix: NATS reserves it for server headers
+// such as Nats-Msg-Id.
+var DefaultHeaderFields = []HeaderField{
+	{Header: "X-Request-Id", Key: contexts.KeyRequestID},
+	{Header: "X-Tenant-Id", Key: contexts.KeyTenantID},
+	{Header: "X-User-Id", Key: contexts.KeyUserID},
+}
+
+// fieldsFromMap appends header -> context key pairs to base, replacing any field
+// already bound to the same header. The result is ordered by header name.
+func fieldsFromMap(base []HeaderField, m map[string]any) []HeaderField {
+	out := make([]HeaderField, 0, len(base)+len(m))
+	for _, f := range base {
+		if _, ok := m[f.Header]; !ok {
+			out = append(out, f)
+		}
+	}
+	names := make([]string, 0, len(m))
+	for h := range m {
+		names = append(names, h)
+	}
+	sort.Strings(names)
+	for _, h := range names {
+		out = append(out, HeaderField{Header: h, Key: m[h]})
+	}
+	return out
+}
+
+// headersFromContext copies the configured string context values into hdr.
+func headersFromContext(ctx context.Context, fields []HeaderField, hdr nats.Header) nats.Header {
+	for _, f := range fields {
+		if v, ok := ctx.Value(f.Key).(string); ok && v != "" {
+			if hdr == nil {
+				hdr = nats.Header{}
+			}
+			hdr.Set(f.Header, v)
+		}
+	}
+	return hdr
+}
+
+// contextFromHeaders restores the configured headers of m into ctx.
+func contextFromHeaders(ctx context.Context, fields []HeaderField, m *nats.Msg) context.Context {
+	if m == nil || len(m.Header) == 0 {
+		return ctx
+	}
+	for _, f := range fields {
+		if v := m.Header.Get(f.Header); v != "" {
+			ctx = context.WithValue(ctx, f.Key, v)
+		}
+	}
+	return ctx
+}
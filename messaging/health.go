@@ -0,0 +1,44 @@
+package messaging
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/nats-io/nats.go"
+	apperrors "github.com/shadowofcards/go-toolkit/errors"
+	"github.com/shadowofcards/go-toolkit/lifecycle"
+)
+
+var ErrUnavailable = apperrors.Define("NATS_UNAVAILABLE", http.StatusServiceUnavailable, "NATS connection is not usable")
+
+// Healthy reports whether nc is connected and, when checkJetStream is set,
+// whether JetStream answers an AccountInfo call within ctx.
+func Healthy(ctx context.Context, nc *nats.Conn, checkJetStream bool) error {
+	if nc == nil || !nc.IsConnected() {
+		e := ErrUnavailable.WithContext("reason", "disconnected")
+		if nc != nil {
+			e = e.WithContext("status", nc.Status().String())
+		}
+		return e
+	}
+	if !checkJetStream {
+		return nil
+	}
+	js, err := nc.JetStream(nats.Context(ctx))
+	if err == nil {
+		_, err = js.AccountInfo(nats.Context(ctx))
+	}
+	if err != nil {
+		return ErrUnavailable.WithError(err).WithContext("reason", "jetstream")
+	}
+	return nil
+}
+
+// HealthCheck wraps Healthy (connection only) for lifecycle.Readiness.Handler;
+// register it with fx.Provide(lifecycle.AsHealthCheck(messaging.HealthCheck)).
+func HealthCheck(nc *nats.Conn) lifecycle.HealthCheck {
+	return lifecycle.HealthCheck{
+		Name:  "nats",
+		Check: func(ctx context.Context) error { return Healthy(ctx, nc, false) },
+	}
+}
@@ -0,0 +1,48 @@
+package messaging
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Heartbeat publishes payload() to subject every interval until ctx is done,
+// starting immediately. Each tick also sets the service_up gauge to 1 on the
+// publisher's metrics recorder; it is reset to 0 on exit. A nil payload sends
+// {"ts": <unix seconds>}. Publish failures are logged and do not stop the loop.
+//
+// Heartbeat blocks; run it in its own goroutine.
+func Heartbeat(ctx context.Context, p *Publisher, subject string, interval time.Duration, payload func() any) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	if payload == nil {
+		payload = func() any { return map[string]int64{"ts": time.Now().Unix()} }
+	}
+	tags := map[string]string{"subject": subject}
+
+	beat := func() {
+		if err := p.Publish(ctx, subject, payload()); err != nil && ctx.Err() == nil {
+			p.log.WarnCtx(ctx, "heartbeat publish failed", zap.String("subject", subject), zap.Error(err))
+		}
+		if p.metrics != nil {
+			p.metrics.GaugeWithTags(ctx, "service_up", 1, tags)
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	beat()
+	for {
+		select {
+		case <-ctx.Done():
+			if p.metrics != nil {
+				p.metrics.GaugeWithTags(context.WithoutCancel(ctx), "service_up", 0, tags)
+			}
+			return
+		case <-ticker.C:
+			beat()
+		}
+	}
+}
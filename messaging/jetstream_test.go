@@ -0,0 +1,39 @@
+package messaging
+
+import (
+	"os"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/xid"
+	"go.uber.org/zap"
+
+	"github.com/shadowofcards/go-toolkit/logging"
+)
+
+// jetStreamConn connects to the JetStream-enabled server at NATS_URL and
+// returns it with a fresh subject whose stream is deleted after the test.
+// Without NATS_URL the test is skipped.
+func jetStreamConn(t *testing.T) (*nats.Conn, string) {
+	t.Helper()
+	url := os.Getenv("NATS_URL")
+	if url == "" {
+		t.Skip("NATS_URL not set; skipping JetStream integration test")
+	}
+	nc, err := nats.Connect(url)
+	if err != nil {
+		t.Fatalf("connect %s: %v", url, err)
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject := "toolkit_test_" + xid.New().String()
+	t.Cleanup(func() {
+		_ = js.DeleteStream(subject)
+		nc.Close()
+	})
+	return nc, subject
+}
+
+func testLogger() *logging.Logger { return &logging.Logger{Logger: zap.NewNop()} }
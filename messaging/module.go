@@ -0,0 +1,42 @@
+package messaging
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/fx"
+
+	"github.com/shadowofcards/go-toolkit/logging"
+)
+
+type ClientParams struct {
+	fx.In
+	Lifecycle  fx.Lifecycle
+	Conn       *nats.Conn
+	Logger     *logging.Logger
+	PubOptions []OptionPublisher `group:"nats_pub_options"`
+	SubOptions []SubOption       `group:"nats_sub_options"`
+}
+
+func providePublisher(p ClientParams) *Publisher {
+	return NewPublisher(p.Conn, p.Logger, p.PubOptions...)
+}
+
+func provideSubscriber(p ClientParams) *Subscriber {
+	s := NewSubscriber(p.Conn, p.Logger, p.SubOptions...)
+	p.Lifecycle.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return s.Stop(ctx)
+		},
+	})
+	return s
+}
+
+// Module provides a *Publisher and a *Subscriber built on the *nats.Conn (see
+// ProvideConn). The Subscriber's Consume calls are stopped and drained on
+// shutdown, before the connection itself is drained.
+func Module() fx.Option {
+	return fx.Options(
+		fx.Provide(providePublisher, provideSubscriber),
+	)
+}
@@ -8,6 +8,7 @@ import (
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 
+	"github.com/shadowofcards/go-toolkit/lifecycle"
 	"github.com/shadowofcards/go-toolkit/logging"
 )
 
@@ -75,7 +76,8 @@ func Connect(opts ...Option) (*nats.Conn, error) {
 type Params struct {
 	fx.In
 	Lifecycle fx.Lifecycle
-	Options   []Option `group:"nats_options"`
+	Options   []Option             `group:"nats_options"`
+	Readiness *lifecycle.Readiness `optional:"true"`
 }
 
 func ProvideConn(p Params) (*nats.Conn, error) {
@@ -85,6 +87,9 @@ func ProvideConn(p Params) (*nats.Conn, error) {
 	}
 	p.Lifecycle.Append(fx.Hook{
 		OnStop: func(_ context.Context) error {
+			if p.Readiness != nil {
+				p.Readiness.SetReady(false)
+			}
 			if pconn := conn; pconn != nil {
 				if hasDrain(p.Options) {
 					_ = pconn.Drain()
@@ -0,0 +1,103 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/shadowofcards/go-toolkit/metrics"
+)
+
+// WorkerPool is a bounded set of goroutines that can be shared by several
+// Subscribers (SubWithWorkerPool), so total handler concurrency is capped per
+// process instead of per subject.
+type WorkerPool struct {
+	name    string
+	size    int
+	tasks   chan func()
+	busy    atomic.Int64
+	metrics metrics.Recorder
+
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+type PoolOption func(*WorkerPool)
+
+func PoolWithName(n string) PoolOption              { return func(p *WorkerPool) { p.name = n } }
+func PoolWithMetrics(m metrics.Recorder) PoolOption { return func(p *WorkerPool) { p.metrics = m } }
+func PoolWithQueueSize(n int) PoolOption {
+	return func(p *WorkerPool) { p.tasks = make(chan func(), n) }
+}
+
+// NewWorkerPool starts size workers. The task queue holds size*4 entries unless
+// PoolWithQueueSize says otherwise.
+func NewWorkerPool(size int, opts ...PoolOption) *WorkerPool {
+	if size < 1 {
+		size = 1
+	}
+	p := &WorkerPool{name: "default", size: size}
+	for _, o := range opts {
+		o(p)
+	}
+	if p.tasks == nil {
+		p.tasks = make(chan func(), size*4)
+	}
+	p.wg.Add(size)
+	for i := 0; i < size; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *WorkerPool) work() {
+	defer p.wg.Done()
+	for task := range p.tasks {
+		p.record(p.busy.Add(1))
+		task()
+		p.record(p.busy.Add(-1))
+	}
+}
+
+func (p *WorkerPool) record(busy int64) {
+	if p.metrics == nil {
+		return
+	}
+	tags := map[string]string{"pool": p.name}
+	ctx := context.Background()
+	p.metrics.GaugeWithTags(ctx, "nats_pool_busy_workers", float64(busy), tags)
+	p.metrics.GaugeWithTags(ctx, "nats_pool_utilization", float64(busy)/float64(p.size), tags)
+}
+
+// Submit queues task, blocking while the queue is full. It returns ctx.Err() if
+// ctx is done first.
+func (p *WorkerPool) Submit(ctx context.Context, task func()) error {
+	select {
+	case p.tasks <- task:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TrySubmit queues task without blocking and reports whether it was accepted.
+func (p *WorkerPool) TrySubmit(task func()) bool {
+	select {
+	case p.tasks <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// Size returns the number of workers.
+func (p *WorkerPool) Size() int { return p.size }
+
+// Close stops the workers once the queued tasks have run. Subscribers using the
+// pool must have stopped consuming before Close is called.
+func (p *WorkerPool) Close() {
+	p.stopOnce.Do(func() {
+		close(p.tasks)
+		p.wg.Wait()
+	})
+}
@@ -2,7 +2,6 @@ package messaging
 
 import (
 	"context"
-	"encoding/json"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -19,6 +18,12 @@ type Publisher struct {
 	prefix       string
 	metrics      metrics.Recorder
 	useJetStream bool
+	stream       streamSpec
+	headerFields []HeaderField
+	codec        Codec
+	gzip         bool
+	gzipMin      int
+	maxInflated  int64
 }
 
 type OptionPublisher func(*Publisher)
@@ -29,34 +34,75 @@ func WithJetStream(enabled bool) OptionPublisher {
 	return func(p *Publisher) { p.useJetStream = enabled }
 }
 
+// WithDuplicateWindow sets the JetStream duplicate window used to reject
+// re-published Nats-Msg-Id values (server default: 2 minutes). The window is a
+// property of the stream: it is applied when EnsureStream creates the stream, and
+// an existing stream keeps its window unless WithStreamUpdate is set.
+func WithDuplicateWindow(d time.Duration) OptionPublisher {
+	return func(p *Publisher) { p.stream.dupWindow = d }
+}
+
+// WithStreamConfig lets callers adjust the StreamConfig (storage, replicas,
+// max age, ...) before EnsureStream creates the stream.
+func WithStreamConfig(fn func(*nats.StreamConfig)) OptionPublisher {
+	return func(p *Publisher) { p.stream.mutate = fn }
+}
+
+// WithStreamUpdate makes EnsureStream update an existing stream whose config
+// differs from the one WithStreamConfig/WithDuplicateWindow describe.
+func WithStreamUpdate() OptionPublisher { return func(p *Publisher) { p.stream.update = true } }
+
+// WithForwardedHeaders sets which context values are copied into NATS headers on
+// publish (default: DefaultHeaderFields). Call with no fields to disable.
+func WithForwardedHeaders(fields ...HeaderField) OptionPublisher {
+	return func(p *Publisher) { p.headerFields = fields }
+}
+
+// WithHeaderPropagation forwards extra context values as NATS headers on top of
+// the current fields (DefaultHeaderFields unless replaced), e.g.
+// {"X-Player-Id": contexts.KeyPlayerID}.
+func WithHeaderPropagation(fields map[string]any) OptionPublisher {
+	return func(p *Publisher) { p.headerFields = fieldsFromMap(p.headerFields, fields) }
+}
+
+// WithCodec sets the payload serializer (default JSONCodec).
+func WithCodec(c Codec) OptionPublisher { return func(p *Publisher) { p.codec = c } }
+
+// WithGzip compresses payloads of at least minBytes and flags them with
+// X-Content-Encoding: gzip; subscribers decompress them transparently.
+func WithGzip(minBytes int) OptionPublisher {
+	return func(p *Publisher) { p.gzip, p.gzipMin = true, minBytes }
+}
+
+// WithMaxDecompressedSize caps how large a gzip reply to Request may inflate
+// to (default: the connection's max payload).
+func WithMaxDecompressedSize(n int64) OptionPublisher {
+	return func(p *Publisher) { p.maxInflated = n }
+}
+
 func NewPublisher(nc *nats.Conn, log *logging.Logger, opts ...OptionPublisher) *Publisher {
 	var js nats.JetStreamContext
 	if jsCtx, err := nc.JetStream(); err == nil {
 		js = jsCtx
 	}
 	p := &Publisher{
-		conn: nc,
-		js:   js,
-		log:  log,
+		conn:         nc,
+		js:           js,
+		log:          log,
+		headerFields: DefaultHeaderFields,
+		codec:        JSONCodec,
 	}
 	for _, opt := range opts {
 		opt(p)
 	}
+	if p.metrics == nil {
+		p.metrics = metrics.NoOp()
+	}
 	return p
 }
 
 func (p *Publisher) EnsureStream(subject string) error {
-	if p.js == nil {
-		return nil
-	}
-	_, err := p.js.StreamInfo(subject)
-	if err == nats.ErrStreamNotFound {
-		_, err = p.js.AddStream(&nats.StreamConfig{
-			Name:     subject,
-			Subjects: []string{subject},
-		})
-	}
-	return err
+	return p.stream.ensure(p.js, subject)
 }
 
 // Publish faz publish com suporte a JetStream deduplicado (Msg-Id) e métricas.
@@ -74,6 +120,9 @@ func (p *Publisher) PublishWithID(ctx context.Context, subject string, msg any,
 	if p.prefix != "" {
 		subject = p.prefix + subject
 	}
+	if err := validateSubject(subject, false); err != nil {
+		return err
+	}
 	if p.useJetStream && p.js != nil {
 		if msgID == "" {
 			msgID = xid.New().String()
@@ -86,7 +135,8 @@ func (p *Publisher) PublishWithID(ctx context.Context, subject string, msg any,
 			start = time.Now()
 		}
 		tags := map[string]string{"subject": subject}
-		data, err := json.Marshal(msg)
+		data, hdr, err := p.encode(ctx, subject, msg,
+			headersFromContext(ctx, p.headerFields, nats.Header{"Nats-Msg-Id": []string{msgID}}))
 		if err != nil {
 			tags["status"] = "marshal_error"
 			if p.metrics != nil {
@@ -98,7 +148,7 @@ func (p *Publisher) PublishWithID(ctx context.Context, subject string, msg any,
 		_, err = p.js.PublishMsg(&nats.Msg{
 			Subject: subject,
 			Data:    data,
-			Header:  nats.Header{"Nats-Msg-Id": []string{msgID}},
+			Header:  hdr,
 		})
 		if err != nil {
 			tags["status"] = "publish_error"
@@ -125,7 +175,7 @@ func (p *Publisher) PublishWithID(ctx context.Context, subject string, msg any,
 		start = time.Now()
 	}
 	tags := map[string]string{"subject": subject}
-	data, err := json.Marshal(msg)
+	data, hdr, err := p.encode(ctx, subject, msg, headersFromContext(ctx, p.headerFields, nats.Header{}))
 	if err != nil {
 		tags["status"] = "marshal_error"
 		if p.metrics != nil {
@@ -134,7 +184,11 @@ func (p *Publisher) PublishWithID(ctx context.Context, subject string, msg any,
 		p.log.ErrorCtx(ctx, "failed to marshal message", zap.String("subject", subject), zap.Error(err))
 		return err
 	}
-	if err := p.conn.Publish(subject, data); err != nil {
+	if err := p.conn.PublishMsg(&nats.Msg{
+		Subject: subject,
+		Data:    data,
+		Header:  hdr,
+	}); err != nil {
 		tags["status"] = "publish_error"
 		if p.metrics != nil {
 			p.metrics.IncWithTags(ctx, "nats_publish_total", 1, tags)
@@ -0,0 +1,35 @@
+package messaging
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDuplicateWindowRejectsRepublishedIDs(t *testing.T) {
+	nc, subject := jetStreamConn(t)
+	const window = 5 * time.Minute
+
+	p := NewPublisher(nc, testLogger(), WithJetStream(true), WithDuplicateWindow(window))
+	ctx := context.Background()
+	for _, id := range []string{"a", "a", "b", "a"} {
+		if err := p.PublishWithID(ctx, subject, map[string]string{"id": id}, id); err != nil {
+			t.Fatalf("publish %s: %v", id, err)
+		}
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := js.StreamInfo(subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Config.Duplicates != window {
+		t.Errorf("duplicate window = %s, want %s", info.Config.Duplicates, window)
+	}
+	if info.State.Msgs != 2 {
+		t.Errorf("stream holds %d messages, want 2 (duplicates within the window rejected)", info.State.Msgs)
+	}
+}
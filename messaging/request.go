@@ -0,0 +1,120 @@
+package messaging
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	apperrors "github.com/shadowofcards/go-toolkit/errors"
+	"github.com/shadowofcards/go-toolkit/metrics"
+	"go.uber.org/zap"
+)
+
+const (
+	errorHeader     = "X-Error"
+	errorCodeHeader = "X-Error-Code"
+)
+
+// ErrRemote is returned by Request when the responder's handler failed. Its
+// code is replaced by the responder's AppError code when there is one.
+var ErrRemote = apperrors.Define("REMOTE_ERROR", http.StatusBadGateway, "remote handler failed")
+
+// defaultRequestTimeout bounds Request when ctx has no deadline.
+const defaultRequestTimeout = 5 * time.Second
+
+type ctxKeyMsg struct{}
+
+// ReplyHandler answers a request; the returned bytes are sent back as-is.
+type ReplyHandler func(ctx context.Context, data []byte) ([]byte, error)
+
+// Request publishes msg on subject, waits for a single reply and decodes it into
+// v, which may be nil. Without a ctx deadline it waits defaultRequestTimeout.
+// Forwarded headers, codec and gzip settings apply as for Publish; JetStream is
+// not involved.
+func (p *Publisher) Request(ctx context.Context, subject string, msg any, v any) error {
+	if p.prefix != "" {
+		subject = p.prefix + subject
+	}
+	if err := validateSubject(subject, false); err != nil {
+		return err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultRequestTimeout)
+		defer cancel()
+	}
+
+	timer := metrics.StartTimer(p.metrics, "nats_request_duration_seconds", map[string]string{"subject": subject})
+	observe := func(status string) {
+		timer.SetTag("status", status).ObserveDuration(ctx)
+	}
+
+	data, hdr, err := p.encode(ctx, subject, msg, headersFromContext(ctx, p.headerFields, nats.Header{}))
+	if err != nil {
+		observe("marshal_error")
+		p.log.ErrorCtx(ctx, "failed to marshal request", zap.String("subject", subject), zap.Error(err))
+		return err
+	}
+	reply, err := p.conn.RequestMsgWithContext(ctx, &nats.Msg{Subject: subject, Data: data, Header: hdr})
+	if err != nil {
+		observe("request_error")
+		p.log.ErrorCtx(ctx, "NATS request failed", zap.String("subject", subject), zap.Error(err))
+		return err
+	}
+	if msg := reply.Header.Get(errorHeader); msg != "" {
+		observe("remote_error")
+		e := ErrRemote.WithMessage(msg).WithContext("subject", subject)
+		if code := reply.Header.Get(errorCodeHeader); code != "" {
+			e = e.WithCode(code)
+		}
+		return e
+	}
+	body, err := payload(reply, inflateLimit(p.conn, p.maxInflated))
+	if err == nil && v != nil && len(body) > 0 {
+		err = codecFor(reply.Header.Get(contentTypeHeader), p.codec).Unmarshal(body, v)
+	}
+	if err != nil {
+		observe("decode_error")
+		p.log.ErrorCtx(ctx, "failed to decode reply", zap.String("subject", subject), zap.Error(err))
+		return err
+	}
+	observe("success")
+	return nil
+}
+
+// ConsumeReply serves requests on subject over core NATS, replying with h's
+// result. A handler error is sent back as X-Error (and X-Error-Code for
+// AppErrors), which Request turns into ErrRemote.
+func (s *Subscriber) ConsumeReply(parent context.Context, subject string, h ReplyHandler) error {
+	parent, done := s.track(parent)
+	defer done()
+	if s.prefix != "" {
+		subject = s.prefix + subject
+	}
+	if err := validateSubject(subject, s.wildcards); err != nil {
+		return err
+	}
+	return s.consumeCore(parent, subject, func(ctx context.Context, data []byte) error {
+		m, _ := ctx.Value(ctxKeyMsg{}).(*nats.Msg)
+		out, herr := h(ctx, data)
+		if m == nil || m.Reply == "" {
+			return herr
+		}
+		resp := &nats.Msg{Subject: m.Reply, Data: out, Header: nats.Header{}}
+		if herr != nil {
+			resp.Data = nil
+			resp.Header.Set(errorHeader, herr.Error())
+			if ae, ok := apperrors.FromError(herr); ok && ae.ErrCode() != "" {
+				resp.Header.Set(errorCodeHeader, ae.ErrCode())
+			}
+		}
+		if err := m.RespondMsg(resp); err != nil {
+			s.log.ErrorCtx(ctx, "failed to send reply", zap.String("subject", subject), zap.Error(err))
+			if herr == nil {
+				return err
+			}
+		}
+		return herr
+	})
+}
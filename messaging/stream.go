@@ -0,0 +1,54 @@
+package messaging
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// streamSpec is the stream setup shared by Publisher and Subscriber.
+type streamSpec struct {
+	dupWindow time.Duration
+	mutate    func(*nats.StreamConfig)
+	update    bool
+}
+
+// apply sets the fields owned by the toolkit and then the caller's mutations.
+func (sp streamSpec) apply(cfg *nats.StreamConfig, subject string) {
+	cfg.Name = subject
+	cfg.Subjects = []string{subject}
+	if sp.dupWindow > 0 {
+		cfg.Duplicates = sp.dupWindow
+	}
+	if sp.mutate != nil {
+		sp.mutate(cfg)
+	}
+}
+
+// ensure creates the stream for subject. An existing stream is left alone
+// unless update is set, in which case the configured fields are re-applied on
+// top of its current config and UpdateStream is called when they differ.
+func (sp streamSpec) ensure(js nats.JetStreamContext, subject string) error {
+	if js == nil {
+		return nil
+	}
+	info, err := js.StreamInfo(subject)
+	if err == nats.ErrStreamNotFound {
+		cfg := nats.StreamConfig{}
+		sp.apply(&cfg, subject)
+		_, err = js.AddStream(&cfg)
+		return err
+	}
+	if err != nil || !sp.update {
+		return err
+	}
+	want := info.Config
+	want.Subjects = append([]string(nil), info.Config.Subjects...)
+	sp.apply(&want, subject)
+	if reflect.DeepEqual(want, info.Config) {
+		return nil
+	}
+	_, err = js.UpdateStream(&want)
+	return err
+}
@@ -0,0 +1,38 @@
+package messaging
+
+import (
+	"net/http"
+	"strings"
+
+	apperrors "github.com/shadowofcards/go-toolkit/errors"
+)
+
+var ErrInvalidSubject = apperrors.Define("INVALID_SUBJECT", http.StatusBadRequest, "invalid NATS subject")
+
+// validateSubject rejects subjects that would silently misroute: empty tokens,
+// whitespace, and wildcards ("*", ">") unless allowWildcards is set.
+func validateSubject(subject string, allowWildcards bool) error {
+	if strings.TrimSpace(subject) == "" {
+		return ErrInvalidSubject.WithContext("subject", subject).WithContext("reason", "empty subject")
+	}
+	if strings.ContainsAny(subject, " \t\r\n") {
+		return ErrInvalidSubject.WithContext("subject", subject).WithContext("reason", "subject contains whitespace")
+	}
+	tokens := strings.Split(subject, ".")
+	for i, tok := range tokens {
+		switch {
+		case tok == "":
+			return ErrInvalidSubject.WithContext("subject", subject).WithContext("reason", "empty token")
+		case tok == "*" || tok == ">":
+			if !allowWildcards {
+				return ErrInvalidSubject.WithContext("subject", subject).WithContext("reason", "wildcards are not allowed")
+			}
+			if tok == ">" && i != len(tokens)-1 {
+				return ErrInvalidSubject.WithContext("subject", subject).WithContext("reason", "'>' must be the last token")
+			}
+		case strings.ContainsAny(tok, "*>"):
+			return ErrInvalidSubject.WithContext("subject", subject).WithContext("reason", "wildcard inside token")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package messaging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+
+	apperrors "github.com/shadowofcards/go-toolkit/errors"
+	"github.com/shadowofcards/go-toolkit/logging"
+)
+
+func TestValidateSubject(t *testing.T) {
+	tests := []struct {
+		subject   string
+		wildcards bool
+		valid     bool
+	}{
+		{"orders.created", false, true},
+		{"tenant-1.orders_v2.created", false, true},
+		{"orders.*", true, true},
+		{"orders.>", true, true},
+		{"*.created.>", true, true},
+
+		{"", false, false},
+		{"   ", false, false},
+		{"orders.created ", false, false},
+		{"orders. created", false, false},
+		{"orders\t.created", false, false},
+		{"orders..created", false, false},
+		{".orders", false, false},
+		{"orders.", false, false},
+		{"orders.*", false, false},
+		{"orders.>", false, false},
+		{"orders.>.created", true, false},
+		{"orders.cre*ted", true, false},
+		{"orders.created>", true, false},
+	}
+	for _, tt := range tests {
+		err := validateSubject(tt.subject, tt.wildcards)
+		if tt.valid && err != nil {
+			t.Errorf("validateSubject(%q, %v) = %v, want nil", tt.subject, tt.wildcards, err)
+		}
+		if !tt.valid && !apperrors.HasCode(err, "INVALID_SUBJECT") {
+			t.Errorf("validateSubject(%q, %v) = %v, want INVALID_SUBJECT", tt.subject, tt.wildcards, err)
+		}
+	}
+}
+
+func TestPublishAndConsumeRejectInvalidSubjects(t *testing.T) {
+	log := &logging.Logger{Logger: zap.NewNop()}
+	ctx := context.Background()
+
+	// The subject is checked before the (unconnected) connection is used.
+	pub := NewPublisher(&nats.Conn{}, log)
+	if err := pub.Publish(ctx, "orders.*", map[string]string{}); !apperrors.HasCode(err, "INVALID_SUBJECT") {
+		t.Errorf("Publish with a wildcard = %v, want INVALID_SUBJECT", err)
+	}
+	if err := pub.Publish(ctx, " ", map[string]string{}); !apperrors.HasCode(err, "INVALID_SUBJECT") {
+		t.Errorf("Publish with a blank subject = %v, want INVALID_SUBJECT", err)
+	}
+
+	noop := func(context.Context, []byte) error { return nil }
+	sub := NewSubscriber(&nats.Conn{}, log)
+	if err := sub.Consume(ctx, "orders.>", noop); !apperrors.HasCode(err, "INVALID_SUBJECT") {
+		t.Errorf("Consume with a wildcard = %v, want INVALID_SUBJECT", err)
+	}
+	wild := NewSubscriber(&nats.Conn{}, log, SubWithAllowWildcards())
+	if err := wild.Consume(ctx, "orders..created", noop); !apperrors.HasCode(err, "INVALID_SUBJECT") {
+		t.Errorf("Consume with an empty token = %v, want INVALID_SUBJECT", err)
+	}
+}
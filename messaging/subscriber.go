@@ -4,7 +4,9 @@ import (
 	"context"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -13,12 +15,25 @@ import (
 	"github.com/shadowofcards/go-toolkit/logging"
 	"github.com/shadowofcards/go-toolkit/metrics"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 )
 
 type ctxKeyNatsMsgID struct{}
+type ctxKeyDeliveryCount struct{}
+
+// DeliveryCount returns how many times JetStream has delivered the message being
+// handled (1 on first delivery). ok is false outside JetStream consumption.
+func DeliveryCount(ctx context.Context) (int, bool) {
+	n, ok := ctx.Value(ctxKeyDeliveryCount{}).(int)
+	return n, ok
+}
 
 type Handler func(ctx context.Context, data []byte) error
 
+// HandlerWithSubject also receives the concrete subject of the message (prefix
+// stripped), e.g. to route on the token matched by a wildcard.
+type HandlerWithSubject func(ctx context.Context, subject string, data []byte) error
+
 type Subscriber struct {
 	conn         *nats.Conn
 	js           nats.JetStreamContext
@@ -29,6 +44,28 @@ type Subscriber struct {
 	deriveCtx    func(context.Context, *nats.Msg) context.Context
 	metrics      metrics.Recorder
 	useJetStream bool
+	ordered      bool
+	headerFields []HeaderField
+	pool         *WorkerPool
+	wildcards    bool
+	codec        Codec
+	limiter      *rate.Limiter
+	maxDeliver   int
+	dlqSubject   string
+	stream       streamSpec
+	drainTimeout time.Duration
+	blocking     bool
+	consumerCfg  *nats.ConsumerConfig
+	fetchBatch   int
+	fetchWait    time.Duration
+	dedup        *dedupCache
+	maxInflated  int64
+
+	stopMu   sync.Mutex
+	stopped  bool
+	stopCtx  context.Context
+	stopFn   context.CancelFunc
+	consumes sync.WaitGroup
 }
 
 type SubOption func(*Subscriber)
@@ -44,107 +81,334 @@ func SubWithJetStream(enabled bool) SubOption {
 	return func(s *Subscriber) { s.useJetStream = enabled }
 }
 
+// SubWithForwardedHeaders sets which NATS headers are restored into the handler
+// context (default: DefaultHeaderFields). Call with no fields to disable.
+func SubWithForwardedHeaders(fields ...HeaderField) SubOption {
+	return func(s *Subscriber) { s.headerFields = fields }
+}
+
+// SubWithHeaderPropagation restores extra NATS headers into the handler context,
+// on top of the current fields (DefaultHeaderFields unless replaced), e.g.
+// {"X-Player-Id": contexts.KeyPlayerID}.
+func SubWithHeaderPropagation(fields map[string]any) SubOption {
+	return func(s *Subscriber) { s.headerFields = fieldsFromMap(s.headerFields, fields) }
+}
+
+// SubWithCodec sets the codec Unmarshal uses for messages without a
+// Content-Type header (default JSONCodec).
+func SubWithCodec(c Codec) SubOption { return func(s *Subscriber) { s.codec = c } }
+
+// SubWithAllowWildcards permits "*" and ">" in consumed subjects. Without it
+// wildcard subjects are rejected as likely typos.
+func SubWithAllowWildcards() SubOption { return func(s *Subscriber) { s.wildcards = true } }
+
+// SubWithWorkerPool runs handlers on a pool shared with other subscribers
+// instead of spawning SubWithConcurrency goroutines per Consume call.
+func SubWithWorkerPool(p *WorkerPool) SubOption { return func(s *Subscriber) { s.pool = p } }
+
+// SubWithOrderedConsumer consumes JetStream subjects through an ordered consumer:
+// ephemeral, strictly ordered, flow-controlled and transparently recreated on
+// gaps or missed heartbeats. Messages are handled one at a time and are not
+// acked, so handler errors are only logged. Unlike the queue/durable mode this is
+// meant for a single instance (e.g. rebuilding a read model); concurrency and
+// queue settings are ignored.
+func SubWithOrderedConsumer() SubOption { return func(s *Subscriber) { s.ordered = true } }
+
+// SubWithRateLimit caps handler invocations at perSecond across all workers of
+// this Subscriber. Workers wait for a token instead of dropping messages, and
+// give up when the Consume context is done.
+func SubWithRateLimit(perSecond int) SubOption {
+	return func(s *Subscriber) {
+		if perSecond > 0 {
+			s.limiter = rate.NewLimiter(rate.Limit(perSecond), perSecond)
+		}
+	}
+}
+
+// SubWithMaxDeliver dead-letters a JetStream message once its handler has failed
+// on the n-th delivery: the raw message is republished to dlqSubject with its
+// original headers plus X-Error, and the original is acked.
+func SubWithMaxDeliver(n int, dlqSubject string) SubOption {
+	return func(s *Subscriber) { s.maxDeliver, s.dlqSubject = n, dlqSubject }
+}
+
+// SubWithStreamConfig lets callers adjust the StreamConfig before EnsureStream
+// creates the stream.
+func SubWithStreamConfig(fn func(*nats.StreamConfig)) SubOption {
+	return func(s *Subscriber) { s.stream.mutate = fn }
+}
+
+// SubWithStreamUpdate makes EnsureStream update an existing stream whose config
+// differs from the one SubWithStreamConfig describes.
+func SubWithStreamUpdate() SubOption { return func(s *Subscriber) { s.stream.update = true } }
+
+// SubWithDrainTimeout bounds how long Consume waits for in-flight core handlers
+// after its context is done. On timeout it logs how many are still running and
+// returns; those handlers keep running in the background.
+func SubWithDrainTimeout(d time.Duration) SubOption {
+	return func(s *Subscriber) { s.drainTimeout = d }
+}
+
+// SubWithBlockingEnqueue makes the core subscriber wait for a free worker
+// instead of dropping messages when the queue is full. Nothing is lost in a
+// burst, but the NATS client buffer then fills up and a sustained backlog ends
+// in slow-consumer errors from the server, which affect the whole connection.
+func SubWithBlockingEnqueue(enabled bool) SubOption {
+	return func(s *Subscriber) { s.blocking = enabled }
+}
+
+// SubWithConsumerConfig creates the JetStream pull consumer from cfg instead of
+// a durable named after the queue. Leave Durable and Name empty for an
+// ephemeral consumer. FilterSubject defaults to the consumed subject and, since
+// pull consumers must ack explicitly, a zero AckPolicy becomes AckExplicitPolicy.
+func SubWithConsumerConfig(cfg *nats.ConsumerConfig) SubOption {
+	return func(s *Subscriber) { s.consumerCfg = cfg }
+}
+
+// SubWithFetch sets how many JetStream messages are pulled per Fetch and how
+// long each Fetch waits for them (default 10 and 2s).
+func SubWithFetch(batch int, maxWait time.Duration) SubOption {
+	return func(s *Subscriber) { s.fetchBatch, s.fetchWait = batch, maxWait }
+}
+
+// SubWithDedup skips messages whose Nats-Msg-Id was processed successfully
+// within window (JetStream messages are still acked). Up to defaultDedupSize
+// ids are kept in memory, least recently seen evicted first.
+func SubWithDedup(window time.Duration) SubOption {
+	return func(s *Subscriber) { s.dedup = newDedupCache(window, defaultDedupSize) }
+}
+
+// SubWithMaxDecompressedSize caps how large a gzip payload may inflate to
+// (default: the connection's max payload). Larger messages fail to decode.
+func SubWithMaxDecompressedSize(n int64) SubOption {
+	return func(s *Subscriber) { s.maxInflated = n }
+}
+
 func NewSubscriber(nc *nats.Conn, log *logging.Logger, opts ...SubOption) *Subscriber {
 	var js nats.JetStreamContext
 	if jsCtx, err := nc.JetStream(); err == nil {
 		js = jsCtx
 	}
 	s := &Subscriber{
-		conn:        nc,
-		js:          js,
-		log:         log,
-		concurrency: runtime.NumCPU(),
-		deriveCtx: func(parent context.Context, m *nats.Msg) context.Context {
-			return context.WithValue(parent, contexts.KeyRequestID, xid.New().String())
-		},
+		conn:         nc,
+		js:           js,
+		log:          log,
+		concurrency:  runtime.NumCPU(),
+		deriveCtx:    defaultDeriveCtx,
+		headerFields: DefaultHeaderFields,
+		codec:        JSONCodec,
+		fetchBatch:   10,
+		fetchWait:    2 * time.Second,
 	}
+	s.stopCtx, s.stopFn = context.WithCancel(context.Background())
 	for _, opt := range opts {
 		opt(s)
 	}
+	if s.metrics == nil {
+		s.metrics = metrics.NoOp()
+	}
 	return s
 }
 
-func (s *Subscriber) EnsureStream(subject string) error {
-	if s.js == nil {
-		return nil
+// defaultDeriveCtx keeps a request id propagated from the publisher and only
+// generates a fresh one when none was forwarded.
+func defaultDeriveCtx(parent context.Context, _ *nats.Msg) context.Context {
+	if rid, ok := parent.Value(contexts.KeyRequestID).(string); ok && rid != "" {
+		return parent
 	}
-	_, err := s.js.StreamInfo(subject)
-	if err == nats.ErrStreamNotFound {
-		_, err = s.js.AddStream(&nats.StreamConfig{
-			Name:     subject,
-			Subjects: []string{subject},
-		})
-	}
-	return err
+	return context.WithValue(parent, contexts.KeyRequestID, xid.New().String())
+}
+
+// messageCtx builds the handler context: forwarded headers and the message codec
+// first, then deriveCtx.
+func (s *Subscriber) messageCtx(parent context.Context, m *nats.Msg) context.Context {
+	ctx := contextFromHeaders(parent, s.headerFields, m)
+	ctx = context.WithValue(ctx, ctxKeyCodec{}, codecFor(m.Header.Get(contentTypeHeader), s.codec))
+	ctx = context.WithValue(ctx, ctxKeyMsg{}, m)
+	return s.deriveCtx(ctx, m)
+}
+
+func (s *Subscriber) EnsureStream(subject string) error {
+	return s.stream.ensure(s.js, subject)
 }
 
 func (s *Subscriber) Consume(parent context.Context, subject string, h Handler) error {
+	parent, done := s.track(parent)
+	defer done()
 	if s.prefix != "" {
 		subject = s.prefix + subject
 	}
+	if err := validateSubject(subject, s.wildcards); err != nil {
+		return err
+	}
 	if s.useJetStream && s.js != nil {
+		if s.ordered {
+			return s.consumeOrdered(parent, subject, h)
+		}
 		return s.consumeJetStream(parent, subject, h)
 	}
 	return s.consumeCore(parent, subject, h)
 }
 
+// Stop ends every running Consume call of s as if their contexts were
+// cancelled and waits, up to ctx, for them to drain and return. Consume calls
+// made after Stop return immediately.
+func (s *Subscriber) Stop(ctx context.Context) error {
+	s.stopMu.Lock()
+	s.stopped = true
+	s.stopMu.Unlock()
+	s.stopFn()
+
+	done := make(chan struct{})
+	go func() {
+		s.consumes.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// track derives the context of a Consume call, cancelled by parent or Stop.
+func (s *Subscriber) track(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	s.stopMu.Lock()
+	defer s.stopMu.Unlock()
+	if s.stopped {
+		cancel()
+		return ctx, func() {}
+	}
+	s.consumes.Add(1)
+	unhook := context.AfterFunc(s.stopCtx, cancel)
+	return ctx, func() {
+		unhook()
+		cancel()
+		s.consumes.Done()
+	}
+}
+
+// ConsumeSubject is Consume for handlers that need the concrete subject. It
+// works in core and JetStream modes; wildcards still need SubWithAllowWildcards.
+func (s *Subscriber) ConsumeSubject(parent context.Context, subject string, h HandlerWithSubject) error {
+	return s.Consume(parent, subject, func(ctx context.Context, data []byte) error {
+		subj := subject
+		if m, ok := ctx.Value(ctxKeyMsg{}).(*nats.Msg); ok {
+			subj = strings.TrimPrefix(m.Subject, s.prefix)
+		}
+		return h(ctx, subj, data)
+	})
+}
+
 func (s *Subscriber) consumeCore(parent context.Context, subject string, h Handler) error {
+	concurrency := s.concurrency
+	if s.pool != nil {
+		concurrency = s.pool.Size()
+	}
 	s.log.InfoCtx(parent, "starting NATS subscription",
 		zap.String("subject", subject),
 		zap.String("queue", s.queue),
-		zap.Int("concurrency", s.concurrency),
+		zap.Int("concurrency", concurrency),
+		zap.Bool("shared_pool", s.pool != nil),
 	)
-	msgCh := make(chan *nats.Msg, s.concurrency*4)
-	var wg sync.WaitGroup
-	for i := 0; i < s.concurrency; i++ {
-		workerID := i
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			for m := range msgCh {
-				ctx := s.deriveCtx(parent, m)
-				start := time.Now()
-				tags := map[string]string{
-					"subject": subject,
-					"queue":   s.queue,
-					"worker":  workerTag(workerID),
-				}
-				if s.metrics != nil {
-					s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "received"}))
+
+	var running atomic.Int64
+	process := func(worker string, m *nats.Msg) {
+		running.Add(1)
+		defer running.Add(-1)
+		s.processCore(parent, subject, worker, h, m)
+	}
+
+	// enqueue hands a message to a worker, without blocking unless
+	// SubWithBlockingEnqueue is set; stop waits for the in-flight messages once
+	// the subscription is drained.
+	var enqueue func(*nats.Msg) bool
+	var stop func()
+	if s.pool != nil {
+		var inflight sync.WaitGroup
+		enqueue = func(m *nats.Msg) bool {
+			inflight.Add(1)
+			task := func() {
+				defer inflight.Done()
+				process("shared", m)
+			}
+			var ok bool
+			if s.blocking {
+				ok = s.pool.Submit(parent, task) == nil
+			} else {
+				ok = s.pool.TrySubmit(task)
+			}
+			if !ok {
+				inflight.Done()
+			}
+			return ok
+		}
+		stop = inflight.Wait
+	} else {
+		msgCh := make(chan *nats.Msg, s.concurrency*4)
+		var wg sync.WaitGroup
+		for i := 0; i < s.concurrency; i++ {
+			wg.Add(1)
+			go func(workerID int) {
+				defer wg.Done()
+				for m := range msgCh {
+					process(workerTag(workerID), m)
 				}
-				if err := h(ctx, m.Data); err != nil {
-					if s.metrics != nil {
-						s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "error"}))
-						s.metrics.ObserveWithTags(ctx, "nats_consume_duration_seconds", time.Since(start).Seconds(), tags)
-					}
-					s.log.ErrorCtx(ctx, "handler error", zap.String("subject", subject), zap.Error(err))
-				} else {
-					if s.metrics != nil {
-						s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "processed"}))
-						s.metrics.ObserveWithTags(ctx, "nats_consume_duration_seconds", time.Since(start).Seconds(), tags)
-					}
-					s.log.DebugCtx(ctx, "message processed", zap.String("subject", subject))
+			}(i)
+		}
+		// gate keeps stop from closing msgCh while a callback is still sending.
+		var gate sync.RWMutex
+		closed := false
+		enqueue = func(m *nats.Msg) bool {
+			gate.RLock()
+			defer gate.RUnlock()
+			if closed {
+				return false
+			}
+			if s.blocking {
+				select {
+				case msgCh <- m:
+					return true
+				case <-parent.Done():
+					return false
 				}
 			}
-		}(workerID)
+			select {
+			case msgCh <- m:
+				return true
+			default:
+				return false
+			}
+		}
+		stop = func() {
+			gate.Lock()
+			closed = true
+			close(msgCh)
+			gate.Unlock()
+			wg.Wait()
+		}
 	}
+
 	cb := func(m *nats.Msg) {
-		ctx := s.deriveCtx(parent, m)
-		select {
-		case msgCh <- m:
-			s.log.DebugCtx(ctx, "message queued", zap.String("subject", subject), zap.Int("queue_length", len(msgCh)))
-		case <-parent.Done():
+		ctx := s.messageCtx(parent, m)
+		if parent.Err() != nil {
 			s.log.InfoCtx(ctx, "stopping enqueue: parent context done", zap.String("subject", subject))
-		default:
-			if s.metrics != nil {
-				s.metrics.IncWithTags(ctx, "nats_consume_total", 1, map[string]string{
-					"subject": subject,
-					"queue":   s.queue,
-					"status":  "dropped",
-				})
-			}
-			s.log.WarnCtx(ctx, "message dropped: queue full", zap.String("subject", subject))
+			return
+		}
+		if enqueue(m) {
+			s.log.DebugCtx(ctx, "message queued", zap.String("subject", subject))
+			return
 		}
+		if s.metrics != nil {
+			s.metrics.IncWithTags(ctx, "nats_consume_total", 1, map[string]string{
+				"subject": subject,
+				"queue":   s.queue,
+				"status":  "dropped",
+			})
+		}
+		s.log.WarnCtx(ctx, "message dropped: queue full", zap.String("subject", subject))
 	}
 	var sub *nats.Subscription
 	var err error
@@ -154,80 +418,312 @@ func (s *Subscriber) consumeCore(parent context.Context, subject string, h Handl
 		sub, err = s.conn.Subscribe(subject, cb)
 	}
 	if err != nil {
-		close(msgCh)
-		wg.Wait()
+		stop()
 		return err
 	}
 	if err = s.conn.Flush(); err != nil {
 		_ = sub.Unsubscribe()
-		close(msgCh)
-		wg.Wait()
+		stop()
 		return err
 	}
 	s.log.InfoCtx(parent, "subscription ready", zap.String("subject", subject), zap.String("queue", s.queue))
 	<-parent.Done()
 	s.log.InfoCtx(parent, "draining subscription", zap.String("subject", subject))
 	_ = sub.Drain()
-	close(msgCh)
-	wg.Wait()
+	if !s.waitDrained(stop) {
+		s.log.WarnCtx(parent, "drain timeout: handlers still running",
+			zap.String("subject", subject),
+			zap.Duration("timeout", s.drainTimeout),
+			zap.Int64("running", running.Load()),
+		)
+		return nil
+	}
 	s.log.InfoCtx(parent, "subscription stopped", zap.String("subject", subject), zap.String("queue", s.queue))
 	return nil
 }
 
+func (s *Subscriber) processCore(parent context.Context, subject, worker string, h Handler, m *nats.Msg) {
+	ctx := s.messageCtx(parent, m)
+	start := time.Now()
+	tags := map[string]string{
+		"subject": subject,
+		"queue":   s.queue,
+		"worker":  worker,
+	}
+	if s.metrics != nil {
+		s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "received"}))
+	}
+	msgID := m.Header.Get("Nats-Msg-Id")
+	if s.duplicate(ctx, subject, msgID, tags) {
+		return
+	}
+	data, err := payload(m, inflateLimit(s.conn, s.maxInflated))
+	if err != nil {
+		if s.metrics != nil {
+			s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "decode_error"}))
+		}
+		s.log.ErrorCtx(ctx, "failed to decode payload", zap.String("subject", subject), zap.Error(err))
+		return
+	}
+	if err := s.throttle(parent, ctx, tags); err != nil {
+		s.log.InfoCtx(ctx, "message abandoned while throttled", zap.String("subject", subject))
+		return
+	}
+	if err := h(ctx, data); err != nil {
+		if s.metrics != nil {
+			s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "error"}))
+			s.metrics.ObserveWithTags(ctx, "nats_consume_duration_seconds", time.Since(start).Seconds(), tags)
+		}
+		s.log.ErrorCtx(ctx, "handler error", zap.String("subject", subject), zap.Error(err))
+		return
+	}
+	if s.metrics != nil {
+		s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "processed"}))
+		s.metrics.ObserveWithTags(ctx, "nats_consume_duration_seconds", time.Since(start).Seconds(), tags)
+	}
+	s.markProcessed(msgID)
+	s.log.DebugCtx(ctx, "message processed", zap.String("subject", subject))
+}
+
 func (s *Subscriber) consumeJetStream(parent context.Context, subject string, h Handler) error {
 	if err := s.EnsureStream(subject); err != nil {
 		return err
 	}
-	consumerName := s.queue
-	if consumerName == "" {
-		consumerName = "default"
-	}
-	sub, err := s.js.PullSubscribe(subject, consumerName, nats.BindStream(subject))
+	sub, consumerName, err := s.pullSubscribe(subject)
 	if err != nil {
 		return err
 	}
 	s.log.InfoCtx(parent, "JetStream subscription ready", zap.String("subject", subject), zap.String("queue", consumerName))
+
+	var inflight sync.WaitGroup
+	defer inflight.Wait()
 	for {
 		select {
 		case <-parent.Done():
 			return nil
 		default:
-			msgs, err := sub.Fetch(10, nats.MaxWait(2*time.Second))
+			msgs, err := sub.Fetch(s.fetchBatch, nats.MaxWait(s.fetchWait))
 			if err != nil && err != nats.ErrTimeout {
 				s.log.ErrorCtx(parent, "JetStream fetch error", zap.Error(err))
 				continue
 			}
 			for _, msg := range msgs {
-				msgID := msg.Header.Get("Nats-Msg-Id")
-				ctx := context.WithValue(parent, ctxKeyNatsMsgID{}, msgID)
-				start := time.Now()
-				tags := map[string]string{
-					"subject": subject,
-					"queue":   consumerName,
-				}
-				if s.metrics != nil {
-					s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "received"}))
-				}
-				err := h(ctx, msg.Data)
-				if err != nil {
-					if s.metrics != nil {
-						s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "error"}))
-						s.metrics.ObserveWithTags(ctx, "nats_consume_duration_seconds", time.Since(start).Seconds(), tags)
-					}
-					s.log.ErrorCtx(ctx, "handler error", zap.String("subject", subject), zap.Error(err))
-					msg.Nak()
+				if s.pool == nil {
+					s.processJetStream(parent, subject, consumerName, h, msg)
 					continue
 				}
-				if s.metrics != nil {
-					s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "processed"}))
-					s.metrics.ObserveWithTags(ctx, "nats_consume_duration_seconds", time.Since(start).Seconds(), tags)
+				inflight.Add(1)
+				if err := s.pool.Submit(parent, func() {
+					defer inflight.Done()
+					s.processJetStream(parent, subject, consumerName, h, msg)
+				}); err != nil {
+					inflight.Done()
+					_ = msg.Nak()
 				}
-				msg.Ack()
 			}
 		}
 	}
 }
 
+// pullSubscribe binds a pull subscription to the configured consumer, or to a
+// durable named after the queue ("default" without one).
+func (s *Subscriber) pullSubscribe(subject string) (*nats.Subscription, string, error) {
+	if s.consumerCfg == nil {
+		name := s.queue
+		if name == "" {
+			name = "default"
+		}
+		sub, err := s.js.PullSubscribe(subject, name, nats.BindStream(subject))
+		return sub, name, err
+	}
+
+	cfg := *s.consumerCfg
+	if cfg.FilterSubject == "" && len(cfg.FilterSubjects) == 0 {
+		cfg.FilterSubject = subject
+	}
+	if cfg.AckPolicy == nats.AckNonePolicy {
+		cfg.AckPolicy = nats.AckExplicitPolicy
+	}
+	info, err := s.js.AddConsumer(subject, &cfg)
+	if err != nil && cfg.Durable != "" {
+		info, err = s.js.UpdateConsumer(subject, &cfg)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	sub, err := s.js.PullSubscribe(subject, "", nats.Bind(subject, info.Name))
+	return sub, info.Name, err
+}
+
+func (s *Subscriber) processJetStream(parent context.Context, subject, consumerName string, h Handler, msg *nats.Msg) {
+	msgID := msg.Header.Get("Nats-Msg-Id")
+	ctx := context.WithValue(s.messageCtx(parent, msg), ctxKeyNatsMsgID{}, msgID)
+	delivered := 0
+	if meta, err := msg.Metadata(); err == nil {
+		delivered = int(meta.NumDelivered)
+		ctx = context.WithValue(ctx, ctxKeyDeliveryCount{}, delivered)
+	}
+	start := time.Now()
+	tags := map[string]string{
+		"subject": subject,
+		"queue":   consumerName,
+	}
+	if s.metrics != nil {
+		s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "received"}))
+	}
+	if s.duplicate(ctx, subject, msgID, tags) {
+		msg.Ack()
+		return
+	}
+	data, err := payload(msg, inflateLimit(s.conn, s.maxInflated))
+	if err != nil {
+		if s.metrics != nil {
+			s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "decode_error"}))
+		}
+		s.log.ErrorCtx(ctx, "failed to decode payload", zap.String("subject", subject), zap.Error(err))
+		msg.Term()
+		return
+	}
+	if err := s.throttle(parent, ctx, tags); err != nil {
+		msg.Nak()
+		return
+	}
+	if err := h(ctx, data); err != nil {
+		if s.metrics != nil {
+			s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "error"}))
+			s.metrics.ObserveWithTags(ctx, "nats_consume_duration_seconds", time.Since(start).Seconds(), tags)
+		}
+		s.log.ErrorCtx(ctx, "handler error", zap.String("subject", subject), zap.Error(err))
+		if s.maxDeliver > 0 && delivered >= s.maxDeliver && s.deadLetter(ctx, subject, msg, err) == nil {
+			msg.Ack()
+			return
+		}
+		msg.Nak()
+		return
+	}
+	if s.metrics != nil {
+		s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "processed"}))
+		s.metrics.ObserveWithTags(ctx, "nats_consume_duration_seconds", time.Since(start).Seconds(), tags)
+	}
+	s.markProcessed(msgID)
+	msg.Ack()
+}
+
+func (s *Subscriber) consumeOrdered(parent context.Context, subject string, h Handler) error {
+	if err := s.EnsureStream(subject); err != nil {
+		return err
+	}
+	tags := map[string]string{
+		"subject": subject,
+		"queue":   "ordered",
+	}
+	cb := func(msg *nats.Msg) {
+		msgID := msg.Header.Get("Nats-Msg-Id")
+		ctx := context.WithValue(s.messageCtx(parent, msg), ctxKeyNatsMsgID{}, msgID)
+		start := time.Now()
+		if s.metrics != nil {
+			s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "received"}))
+		}
+		status := "processed"
+		if data, err := payload(msg, inflateLimit(s.conn, s.maxInflated)); err != nil {
+			status = "decode_error"
+			s.log.ErrorCtx(ctx, "failed to decode payload", zap.String("subject", subject), zap.Error(err))
+		} else if err := h(ctx, data); err != nil {
+			status = "error"
+			s.log.ErrorCtx(ctx, "handler error", zap.String("subject", subject), zap.Error(err))
+		}
+		if s.metrics != nil {
+			s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": status}))
+			s.metrics.ObserveWithTags(ctx, "nats_consume_duration_seconds", time.Since(start).Seconds(), tags)
+		}
+	}
+	sub, err := s.js.Subscribe(subject, cb, nats.OrderedConsumer(), nats.BindStream(subject))
+	if err != nil {
+		return err
+	}
+	s.log.InfoCtx(parent, "JetStream ordered subscription ready", zap.String("subject", subject))
+	<-parent.Done()
+	_ = sub.Unsubscribe()
+	s.log.InfoCtx(parent, "JetStream ordered subscription stopped", zap.String("subject", subject))
+	return nil
+}
+
+// waitDrained runs stop, giving up after drainTimeout when one is set.
+func (s *Subscriber) waitDrained(stop func()) bool {
+	if s.drainTimeout <= 0 {
+		stop()
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		stop()
+		close(done)
+	}()
+	t := time.NewTimer(s.drainTimeout)
+	defer t.Stop()
+	select {
+	case <-done:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+// deadLetter republishes msg untouched (data and headers) to the DLQ subject,
+// adding the last handler error as X-Error.
+func (s *Subscriber) deadLetter(ctx context.Context, subject string, msg *nats.Msg, cause error) error {
+	hdr := nats.Header{}
+	for k, v := range msg.Header {
+		hdr[k] = append([]string(nil), v...)
+	}
+	hdr.Set("X-Error", cause.Error())
+	if err := s.conn.PublishMsg(&nats.Msg{Subject: s.dlqSubject, Data: msg.Data, Header: hdr}); err != nil {
+		s.log.ErrorCtx(ctx, "failed to dead-letter message",
+			zap.String("subject", subject), zap.String("dlq", s.dlqSubject), zap.Error(err))
+		return err
+	}
+	if s.metrics != nil {
+		s.metrics.IncWithTags(ctx, "nats_dlq_total", 1, map[string]string{
+			"subject": subject,
+			"dlq":     s.dlqSubject,
+		})
+	}
+	s.log.WarnCtx(ctx, "message dead-lettered",
+		zap.String("subject", subject), zap.String("dlq", s.dlqSubject), zap.Error(cause))
+	return nil
+}
+
+// duplicate reports (and counts) a message already processed within the
+// SubWithDedup window.
+func (s *Subscriber) duplicate(ctx context.Context, subject, msgID string, tags map[string]string) bool {
+	if s.dedup == nil || msgID == "" || !s.dedup.seen(msgID) {
+		return false
+	}
+	if s.metrics != nil {
+		s.metrics.IncWithTags(ctx, "nats_consume_total", 1, mergeTags(tags, map[string]string{"status": "deduplicated"}))
+	}
+	s.log.DebugCtx(ctx, "duplicate message skipped", zap.String("subject", subject), zap.String("msg_id", msgID))
+	return true
+}
+
+func (s *Subscriber) markProcessed(msgID string) {
+	if s.dedup != nil && msgID != "" {
+		s.dedup.mark(msgID)
+	}
+}
+
+// throttle waits for a rate limiter token, counting the messages that had to
+// wait. It fails only when parent is done.
+func (s *Subscriber) throttle(parent, ctx context.Context, tags map[string]string) error {
+	if s.limiter == nil || s.limiter.Allow() {
+		return nil
+	}
+	if s.metrics != nil {
+		s.metrics.IncWithTags(ctx, "nats_consume_throttled_total", 1, tags)
+	}
+	return s.limiter.Wait(parent)
+}
+
 func mergeTags(a, b map[string]string) map[string]string {
 	tags := make(map[string]string, len(a)+len(b))
 	for k, v := range a {
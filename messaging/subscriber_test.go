@@ -0,0 +1,69 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestOrderedConsumerKeepsOrderAcrossReconnect(t *testing.T) {
+	nc, subject := jetStreamConn(t)
+	const total = 20
+
+	p := NewPublisher(nc, testLogger(), WithJetStream(true))
+	publish := func(from, to int) {
+		for i := from; i < to; i++ {
+			if err := p.Publish(context.Background(), subject, map[string]int{"n": i}); err != nil {
+				t.Fatalf("publish %d: %v", i, err)
+			}
+		}
+	}
+	publish(0, total/2)
+
+	got := make(chan int, total)
+	s := NewSubscriber(nc, testLogger(), SubWithJetStream(true), SubWithOrderedConsumer())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = s.Consume(ctx, subject, func(_ context.Context, data []byte) error {
+			var m map[string]int
+			if err := json.Unmarshal(data, &m); err != nil {
+				return err
+			}
+			got <- m["n"]
+			return nil
+		})
+	}()
+
+	next := 0
+	expect := func(upTo int) {
+		t.Helper()
+		for next < upTo {
+			select {
+			case n := <-got:
+				if n != next {
+					t.Fatalf("received %d, want %d", n, next)
+				}
+				next++
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for message %d", next)
+			}
+		}
+	}
+	expect(total / 2)
+
+	if err := nc.ForceReconnect(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !nc.IsConnected() {
+		if time.Now().After(deadline) {
+			t.Fatal("connection did not come back")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	publish(total/2, total)
+	expect(total)
+}
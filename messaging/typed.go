@@ -0,0 +1,32 @@
+package messaging
+
+import (
+	"context"
+	"net/http"
+
+	apperrors "github.com/shadowofcards/go-toolkit/errors"
+)
+
+// ErrDecodeMessage has its own code: a malformed message is the producer's
+// fault (400), unlike httpclient's DECODE_ERROR for an unreadable upstream
+// response (500).
+var ErrDecodeMessage = apperrors.Define("MESSAGE_DECODE_ERROR", http.StatusBadRequest, "failed to decode message")
+
+// PublishJSON publishes v with the publisher's codec (JSON unless WithCodec
+// says otherwise).
+func PublishJSON[T any](ctx context.Context, p *Publisher, subject string, v T) error {
+	return p.Publish(ctx, subject, v)
+}
+
+// ConsumeJSON is Consume with the payload decoded into T before h runs. A
+// payload that does not decode is reported as ErrDecodeMessage without calling
+// h, so it is logged and, on JetStream, nak'ed like any handler error.
+func ConsumeJSON[T any](ctx context.Context, s *Subscriber, subject string, h func(ctx context.Context, v T) error) error {
+	return s.Consume(ctx, subject, func(ctx context.Context, data []byte) error {
+		var v T
+		if err := Unmarshal(ctx, data, &v); err != nil {
+			return ErrDecodeMessage.WithError(err).WithContext("subject", subject)
+		}
+		return h(ctx, v)
+	})
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+// WithAsyncBatching makes writes non-blocking: points are buffered and sent in
+// batches of batchSize or every flushInterval (Config.FlushInterval when <= 0).
+// Write errors are no longer returned; use WithWriteErrorHandler to observe
+// them, and call Close to flush on shutdown.
+func WithAsyncBatching(batchSize int, flushInterval time.Duration) Option {
+	return func(c *Config) {
+		c.AsyncBatching = true
+		c.BatchSize = batchSize
+		if flushInterval > 0 {
+			c.FlushInterval = flushInterval
+		}
+	}
+}
+
+// WithWriteErrorHandler receives errors from asynchronous writes.
+func WithWriteErrorHandler(fn func(error)) Option {
+	return func(c *Config) { c.OnWriteError = fn }
+}
+
+func (cfg Config) clientOptions() *influxdb2.Options {
+	opts := influxdb2.DefaultOptions()
+	if !cfg.AsyncBatching {
+		return opts
+	}
+	if cfg.BatchSize > 0 {
+		opts.SetBatchSize(uint(cfg.BatchSize))
+	}
+	if cfg.FlushInterval > 0 {
+		opts.SetFlushInterval(uint(cfg.FlushInterval.Milliseconds()))
+	}
+	return opts
+}
+
+// asyncFor returns the non-blocking writer for the bucket resolved from ctx.
+func (c *Client) asyncFor(ctx context.Context) api.WriteAPI {
+	bucket := c.cfg.Bucket
+	if c.cfg.BucketResolver != nil {
+		if b := c.cfg.BucketResolver(ctx); b != "" {
+			bucket = b
+		}
+	}
+
+	c.mu.RLock()
+	w, ok := c.async[bucket]
+	c.mu.RUnlock()
+	if ok {
+		return w
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if w, ok = c.async[bucket]; !ok {
+		w = c.cli.WriteAPI(c.cfg.Org, bucket)
+		if fn := c.cfg.OnWriteError; fn != nil {
+			go func(errs <-chan error) {
+				for err := range errs {
+					fn(err)
+				}
+			}(w.Errors())
+		}
+		c.async[bucket] = w
+	}
+	return w
+}
+
+func (c *Client) writeAsync(ctx context.Context, point *write.Point) error {
+	c.asyncFor(ctx).WritePoint(point)
+	return nil
+}
+
+// Close flushes buffered points and releases the InfluxDB client. It returns
+// ctx.Err() if ctx ends before the flush completes.
+func (c *Client) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		c.mu.RLock()
+		for _, w := range c.async {
+			w.Flush()
+		}
+		c.mu.RUnlock()
+		c.cli.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -2,10 +2,13 @@ package metrics
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/domain"
 	"github.com/shadowofcards/go-toolkit/contexts"
 )
 
@@ -35,11 +38,23 @@ type Config struct {
 	DefaultTags      map[string]string
 	ExtraTags        map[string]string
 	MaxRetentionDays int
+	Namespace        string
+	ValidateOnStart  bool
+	BucketResolver   func(ctx context.Context) string
+	AsyncBatching    bool
+	BatchSize        int
+	OnWriteError     func(error)
+	TagSanitizers    []TagSanitizer
 }
 
 type Client struct {
+	cli      influxdb2.Client
 	writeAPI api.WriteAPIBlocking
 	cfg      Config
+
+	mu      sync.RWMutex
+	buckets map[string]api.WriteAPIBlocking
+	async   map[string]api.WriteAPI
 }
 
 func New(opts ...Option) (*Client, error) {
@@ -53,9 +68,41 @@ func New(opts ...Option) (*Client, error) {
 		o(&cfg)
 	}
 
-	cli := influxdb2.NewClient(cfg.InfluxURL, cfg.Token)
+	cli := influxdb2.NewClientWithOptions(cfg.InfluxURL, cfg.Token, cfg.clientOptions())
 	writeAPI := cli.WriteAPIBlocking(cfg.Org, cfg.Bucket)
-	return &Client{writeAPI: writeAPI, cfg: cfg}, nil
+	c := &Client{
+		cli:      cli,
+		writeAPI: writeAPI,
+		cfg:      cfg,
+		buckets:  map[string]api.WriteAPIBlocking{cfg.Bucket: writeAPI},
+		async:    map[string]api.WriteAPI{},
+	}
+
+	if cfg.ValidateOnStart {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := c.Ping(ctx); err != nil {
+			cli.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Ping checks the InfluxDB health endpoint. It does not validate the token.
+func (c *Client) Ping(ctx context.Context) error {
+	h, err := c.cli.Health(ctx)
+	if err != nil {
+		return fmt.Errorf("metrics: influxdb unreachable at %q: %w", c.cfg.InfluxURL, err)
+	}
+	if h.Status != domain.HealthCheckStatusPass {
+		msg := ""
+		if h.Message != nil {
+			msg = *h.Message
+		}
+		return fmt.Errorf("metrics: influxdb unhealthy (%s): %s", h.Status, msg)
+	}
+	return nil
 }
 
 var _ Recorder = (*Client)(nil)
@@ -64,6 +111,19 @@ func WithURL(u string) Option    { return func(c *Config) { c.InfluxURL = u } }
 func WithToken(t string) Option  { return func(c *Config) { c.Token = t } }
 func WithOrg(o string) Option    { return func(c *Config) { c.Org = o } }
 func WithBucket(b string) Option { return func(c *Config) { c.Bucket = b } }
+
+// WithValidateOnStart makes New fail when InfluxDB is unreachable instead of
+// silently dropping metrics later. Off by default for offline/test setups.
+func WithValidateOnStart() Option { return func(c *Config) { c.ValidateOnStart = true } }
+
+// WithBucketResolver picks the bucket for each write from ctx (e.g. by tenant
+// tier). An empty result falls back to the configured bucket.
+func WithBucketResolver(fn func(ctx context.Context) string) Option {
+	return func(c *Config) { c.BucketResolver = fn }
+}
+
+// WithNamespace prefixes every measurement name with "<prefix>_".
+func WithNamespace(prefix string) Option { return func(c *Config) { c.Namespace = prefix } }
 func WithDefaultTags(tags map[string]string) Option {
 	return func(c *Config) {
 		for k, v := range tags {
@@ -126,6 +186,40 @@ func (c *Client) write(ctx context.Context, measurement string, fields map[strin
 		}
 	}
 
+	c.sanitize(tags)
+
+	if c.cfg.Namespace != "" {
+		measurement = c.cfg.Namespace + "_" + measurement
+	}
+
 	point := influxdb2.NewPoint(measurement, tags, fields, time.Now().UTC())
-	return c.writeAPI.WritePoint(ctx, point)
+	if c.cfg.AsyncBatching {
+		return c.writeAsync(ctx, point)
+	}
+	return c.writerFor(ctx).WritePoint(ctx, point)
+}
+
+func (c *Client) writerFor(ctx context.Context) api.WriteAPIBlocking {
+	if c.cfg.BucketResolver == nil {
+		return c.writeAPI
+	}
+	bucket := c.cfg.BucketResolver(ctx)
+	if bucket == "" || bucket == c.cfg.Bucket {
+		return c.writeAPI
+	}
+
+	c.mu.RLock()
+	w, ok := c.buckets[bucket]
+	c.mu.RUnlock()
+	if ok {
+		return w
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if w, ok = c.buckets[bucket]; !ok {
+		w = c.cli.WriteAPIBlocking(c.cfg.Org, bucket)
+		c.buckets[bucket] = w
+	}
+	return w
 }
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// influxStub records the line protocol posted to /api/v2/write.
+type influxStub struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (s *influxStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/v2/write" {
+		http.NotFound(w, r)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	for _, l := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+		if l != "" {
+			s.lines = append(s.lines, l)
+		}
+	}
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *influxStub) measurements() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]string, len(s.lines))
+	for i, l := range s.lines {
+		out[i], _, _ = strings.Cut(l, ",")
+		out[i], _, _ = strings.Cut(out[i], " ")
+	}
+	return out
+}
+
+func TestNamespaceAppliesToEveryMethod(t *testing.T) {
+	stub := &influxStub{}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	c, err := New(WithURL(srv.URL), WithOrg("org"), WithBucket("bucket"), WithNamespace("svc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(context.Background())
+	ctx := context.Background()
+	tags := map[string]string{"k": "v"}
+	calls := []error{
+		c.Inc(ctx, "inc", 1),
+		c.IncWithTags(ctx, "inc_tags", 1, tags),
+		c.Gauge(ctx, "gauge", 1),
+		c.GaugeWithTags(ctx, "gauge_tags", 1, tags),
+		c.Observe(ctx, "observe", 1),
+		c.ObserveWithTags(ctx, "observe_tags", 1, tags),
+	}
+	for i, err := range calls {
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+
+	want := []string{"svc_inc", "svc_inc_tags", "svc_gauge", "svc_gauge_tags", "svc_observe", "svc_observe_tags"}
+	got := stub.measurements()
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("measurements = %v, want %v", got, want)
+	}
+}
+
+func TestNoNamespaceKeepsNames(t *testing.T) {
+	stub := &influxStub{}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	c, err := New(WithURL(srv.URL), WithOrg("org"), WithBucket("bucket"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(context.Background())
+	if err := c.Inc(context.Background(), "http_requests_total", 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := stub.measurements(); len(got) != 1 || got[0] != "http_requests_total" {
+		t.Fatalf("measurements = %v, want [http_requests_total]", got)
+	}
+}
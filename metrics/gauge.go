@@ -0,0 +1,19 @@
+package metrics
+
+import "context"
+
+// GaugeAdder is implemented by recorders that can move a gauge by a delta
+// instead of setting it, such as the Prometheus recorder.
+type GaugeAdder interface {
+	AddGaugeWithTags(ctx context.Context, name string, delta float64, tags map[string]string) error
+}
+
+// AddGauge moves gauge name by delta (e.g. +1/-1 around an in-flight request).
+// Recorders without GaugeAdder receive the delta through GaugeWithTags, which
+// suits backends that sum the points at query time such as InfluxDB.
+func AddGauge(ctx context.Context, rec Recorder, name string, delta float64, tags map[string]string) error {
+	if a, ok := rec.(GaugeAdder); ok {
+		return a.AddGaugeWithTags(ctx, name, delta, tags)
+	}
+	return rec.GaugeWithTags(ctx, name, delta, tags)
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+)
+
+type Params struct {
+	fx.In
+	Lifecycle fx.Lifecycle
+	Options   []Option `group:"metrics_options"`
+}
+
+type factory struct{ base []Option }
+
+// NewFactory returns a Factory whose recorders apply base before the options
+// passed to NewRecorder. Without an Influx URL it hands out NoOp recorders.
+// Callers own the returned recorders and should Close them (see Client.Close).
+func NewFactory(base ...Option) Factory { return factory{base: base} }
+
+func (f factory) NewRecorder(opts ...Option) (Recorder, error) {
+	all := append(append([]Option{}, f.base...), opts...)
+	cfg := Config{DefaultTags: map[string]string{}, ExtraTags: map[string]string{}}
+	for _, o := range all {
+		o(&cfg)
+	}
+	if cfg.InfluxURL == "" {
+		return NoOp(), nil
+	}
+	return New(all...)
+}
+
+func provideFactory(p Params) Factory {
+	return NewFactory(p.Options...)
+}
+
+func provideRecorder(p Params, f Factory) (Recorder, error) {
+	rec, err := f.NewRecorder()
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := rec.(*Client); ok {
+		p.Lifecycle.Append(fx.Hook{
+			OnStop: func(ctx context.Context) error { return c.Close(ctx) },
+		})
+	}
+	return rec, nil
+}
+
+// Module provides a Recorder built from the "metrics_options" group, flushed
+// and closed on stop, and a Factory for additional recorders. Without an
+// Influx URL (WithURL) the Recorder is NoOp.
+func Module() fx.Option {
+	return fx.Options(
+		fx.Provide(provideFactory, provideRecorder),
+	)
+}
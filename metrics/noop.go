@@ -0,0 +1,16 @@
+package metrics
+
+import "context"
+
+type noop struct{}
+
+// NoOp returns a Recorder that discards everything. Constructors across the
+// toolkit use it when no recorder is configured.
+func NoOp() Recorder { return noop{} }
+
+func (noop) Inc(context.Context, string, int64) error                                  { return nil }
+func (noop) Gauge(context.Context, string, float64) error                              { return nil }
+func (noop) IncWithTags(context.Context, string, int64, map[string]string) error       { return nil }
+func (noop) GaugeWithTags(context.Context, string, float64, map[string]string) error   { return nil }
+func (noop) Observe(context.Context, string, float64) error                            { return nil }
+func (noop) ObserveWithTags(context.Context, string, float64, map[string]string) error { return nil }
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNoOpNeverFailsOrAllocates(t *testing.T) {
+	rec := NoOp()
+	var nilCtx context.Context
+	tags := map[string]string{"k": "v"}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, ctx := range []context.Context{context.Background(), nilCtx} {
+			for _, err := range []error{
+				rec.Inc(ctx, "n", 1),
+				rec.Gauge(ctx, "n", 1),
+				rec.IncWithTags(ctx, "n", 1, tags),
+				rec.GaugeWithTags(ctx, "n", 1, nil),
+				rec.Observe(ctx, "n", 1),
+				rec.ObserveWithTags(ctx, "n", 1, tags),
+				AddGauge(ctx, rec, "n", -1, tags),
+			} {
+				if err != nil {
+					t.Fatalf("NoOp returned %v", err)
+				}
+			}
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("NoOp allocated %v times per run", allocs)
+	}
+}
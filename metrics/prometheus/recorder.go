@@ -0,0 +1,284 @@
+// Package prometheus implements metrics.Recorder on top of the Prometheus
+// client, exposing the collected series through Handler for scraping.
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/shadowofcards/go-toolkit/contexts"
+	"github.com/shadowofcards/go-toolkit/metrics"
+)
+
+type Option func(*Recorder)
+
+// Recorder maps Inc* to counters, Gauge* to gauges and Observe* to histograms.
+// Gauge* set the gauge; AddGaugeWithTags (see metrics.AddGauge) moves it.
+//
+// Like the InfluxDB client, tenant_id and region are taken from the context;
+// they are part of every label set unless WithoutContextLabels is given.
+//
+// Prometheus needs a fixed label set per metric. It is taken from WithLabels
+// when declared, otherwise from the tag keys of the first call for that
+// metric; later tags outside the set are dropped and missing ones are empty.
+type Recorder struct {
+	reg       *prom.Registry
+	namespace string
+	buckets   []float64
+	constant  prom.Labels
+	declared  map[string][]string
+	ctxLabels bool
+
+	mu         sync.Mutex
+	counters   map[string]*vec[*prom.CounterVec]
+	gauges     map[string]*vec[*prom.GaugeVec]
+	histograms map[string]*vec[*prom.HistogramVec]
+}
+
+type vec[V any] struct {
+	v      V
+	labels []string
+}
+
+var (
+	_ metrics.Recorder   = (*Recorder)(nil)
+	_ metrics.GaugeAdder = (*Recorder)(nil)
+)
+
+// contextLabels are filled from the context like the InfluxDB client's tags.
+var contextLabels = []struct {
+	label string
+	key   any
+}{
+	{"tenant_id", contexts.KeyTenantID},
+	{"region", contexts.KeyRegion},
+}
+
+func New(opts ...Option) *Recorder {
+	r := &Recorder{
+		reg:        prom.NewRegistry(),
+		buckets:    prom.DefBuckets,
+		declared:   map[string][]string{},
+		ctxLabels:  true,
+		counters:   map[string]*vec[*prom.CounterVec]{},
+		gauges:     map[string]*vec[*prom.GaugeVec]{},
+		histograms: map[string]*vec[*prom.HistogramVec]{},
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// WithRegistry registers the metrics on reg instead of a private registry.
+func WithRegistry(reg *prom.Registry) Option { return func(r *Recorder) { r.reg = reg } }
+
+// WithNamespace prefixes every metric name with "<prefix>_".
+func WithNamespace(prefix string) Option { return func(r *Recorder) { r.namespace = prefix } }
+
+// WithBuckets sets the histogram buckets used by Observe (default prom.DefBuckets).
+func WithBuckets(buckets ...float64) Option { return func(r *Recorder) { r.buckets = buckets } }
+
+// WithConstLabels adds fixed labels to every metric, like metrics.WithDefaultTags.
+func WithConstLabels(labels map[string]string) Option {
+	return func(r *Recorder) { r.constant = prom.Labels(labels) }
+}
+
+// WithLabels declares the label set of metric name up front.
+func WithLabels(name string, labels ...string) Option {
+	return func(r *Recorder) { r.declared[name] = labels }
+}
+
+// WithoutContextLabels stops adding tenant_id and region from the context,
+// e.g. to keep series cardinality low with many tenants.
+func WithoutContextLabels() Option { return func(r *Recorder) { r.ctxLabels = false } }
+
+// Handler serves the registry in the Prometheus exposition format.
+func (r *Recorder) Handler() http.Handler {
+	return promhttp.HandlerFor(r.reg, promhttp.HandlerOpts{Registry: r.reg})
+}
+
+func (r *Recorder) Inc(ctx context.Context, name string, delta int64) error {
+	return r.IncWithTags(ctx, name, delta, nil)
+}
+
+func (r *Recorder) Gauge(ctx context.Context, name string, value float64) error {
+	return r.GaugeWithTags(ctx, name, value, nil)
+}
+
+func (r *Recorder) Observe(ctx context.Context, name string, value float64) error {
+	return r.ObserveWithTags(ctx, name, value, nil)
+}
+
+func (r *Recorder) IncWithTags(ctx context.Context, name string, delta int64, tags map[string]string) error {
+	if delta < 0 {
+		return fmt.Errorf("metrics: counter %q cannot decrease", name)
+	}
+	tags = r.withContext(ctx, tags)
+	v, err := get(r, r.counters, name, tags, func(opts prom.Opts, labels []string) *prom.CounterVec {
+		return prom.NewCounterVec(prom.CounterOpts(opts), labels)
+	})
+	if err != nil {
+		return err
+	}
+	v.v.With(values(v.labels, tags)).Add(float64(delta))
+	return nil
+}
+
+func (r *Recorder) GaugeWithTags(ctx context.Context, name string, value float64, tags map[string]string) error {
+	g, err := r.gauge(ctx, name, tags)
+	if err != nil {
+		return err
+	}
+	g.Set(value)
+	return nil
+}
+
+// AddGaugeWithTags adds delta to the gauge.
+func (r *Recorder) AddGaugeWithTags(ctx context.Context, name string, delta float64, tags map[string]string) error {
+	g, err := r.gauge(ctx, name, tags)
+	if err != nil {
+		return err
+	}
+	g.Add(delta)
+	return nil
+}
+
+func (r *Recorder) gauge(ctx context.Context, name string, tags map[string]string) (prom.Gauge, error) {
+	tags = r.withContext(ctx, tags)
+	v, err := get(r, r.gauges, name, tags, func(opts prom.Opts, labels []string) *prom.GaugeVec {
+		return prom.NewGaugeVec(prom.GaugeOpts(opts), labels)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.v.With(values(v.labels, tags)), nil
+}
+
+func (r *Recorder) ObserveWithTags(ctx context.Context, name string, value float64, tags map[string]string) error {
+	tags = r.withContext(ctx, tags)
+	v, err := get(r, r.histograms, name, tags, func(opts prom.Opts, labels []string) *prom.HistogramVec {
+		return prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Name:        opts.Name,
+			Help:        opts.Help,
+			ConstLabels: opts.ConstLabels,
+			Buckets:     r.buckets,
+		}, labels)
+	})
+	if err != nil {
+		return err
+	}
+	v.v.With(values(v.labels, tags)).Observe(value)
+	return nil
+}
+
+// get returns the vector for name, creating and registering it on first use.
+func get[V prom.Collector](
+	r *Recorder,
+	m map[string]*vec[V],
+	name string,
+	tags map[string]string,
+	build func(prom.Opts, []string) V,
+) (*vec[V], error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if v, ok := m[name]; ok {
+		return v, nil
+	}
+
+	labels, ok := r.declared[name]
+	if !ok {
+		for k := range tags {
+			labels = append(labels, k)
+		}
+		sort.Strings(labels)
+	}
+	if r.ctxLabels {
+		for _, cl := range contextLabels {
+			if !slices.Contains(labels, cl.label) {
+				labels = append(labels, cl.label)
+			}
+		}
+	}
+	sanitized := make([]string, len(labels))
+	for i, l := range labels {
+		sanitized[i] = sanitize(l)
+	}
+
+	col := build(prom.Opts{
+		Namespace:   sanitize(r.namespace),
+		Name:        sanitize(name),
+		Help:        name,
+		ConstLabels: r.constant,
+	}, sanitized)
+	if err := r.reg.Register(col); err != nil {
+		return nil, err
+	}
+	v := &vec[V]{v: col, labels: labels}
+	m[name] = v
+	return v, nil
+}
+
+// withContext returns tags plus the context labels found in ctx, leaving the
+// caller's map untouched.
+func (r *Recorder) withContext(ctx context.Context, tags map[string]string) map[string]string {
+	if !r.ctxLabels || ctx == nil {
+		return tags
+	}
+	out, copied := tags, false
+	for _, cl := range contextLabels {
+		s, _ := ctx.Value(cl.key).(string)
+		if s == "" {
+			continue
+		}
+		if !copied {
+			copied = true
+			out = make(map[string]string, len(tags)+len(contextLabels))
+			for k, v := range tags {
+				out[k] = v
+			}
+		}
+		out[cl.label] = s
+	}
+	return out
+}
+
+// values maps tags onto the fixed label set, keyed by sanitized name.
+func values(labels []string, tags map[string]string) prom.Labels {
+	out := make(prom.Labels, len(labels))
+	for _, l := range labels {
+		out[sanitize(l)] = tags[l]
+	}
+	return out
+}
+
+// sanitize turns name into a valid Prometheus metric or label name.
+func sanitize(name string) string {
+	if name == "" {
+		return ""
+	}
+	var b strings.Builder
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+			b.WriteRune(c)
+		case c >= '0' && c <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(c)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
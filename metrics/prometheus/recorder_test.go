@@ -0,0 +1,84 @@
+package prometheus
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shadowofcards/go-toolkit/contexts"
+	"github.com/shadowofcards/go-toolkit/metrics"
+)
+
+// scrape returns the exposition lines of r starting with prefix.
+func scrape(t *testing.T, r *Recorder, prefix string) []string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	var out []string
+	for _, l := range strings.Split(string(body), "\n") {
+		if strings.HasPrefix(l, prefix) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func TestAddGaugeTracksInFlight(t *testing.T) {
+	r := New(WithoutContextLabels())
+	ctx := context.Background()
+	tags := map[string]string{"path": "/x"}
+	for _, d := range []float64{1, 1, 1, -1} {
+		if err := metrics.AddGauge(ctx, r, "http_in_flight_requests", d, tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := scrape(t, r, "http_in_flight_requests")
+	if len(got) != 1 || got[0] != `http_in_flight_requests{path="/x"} 2` {
+		t.Fatalf("got %q, want the gauge at 2", got)
+	}
+}
+
+func TestGaugeWithTagsSets(t *testing.T) {
+	r := New(WithoutContextLabels())
+	ctx := context.Background()
+	_ = r.GaugeWithTags(ctx, "room_size", 5, nil)
+	_ = r.GaugeWithTags(ctx, "room_size", 3, nil)
+	if got := scrape(t, r, "room_size"); len(got) != 1 || got[0] != "room_size 3" {
+		t.Fatalf("got %q, want room_size 3", got)
+	}
+}
+
+func TestContextLabels(t *testing.T) {
+	r := New(WithNamespace("svc"))
+	ctx := context.WithValue(context.Background(), contexts.KeyTenantID, "t1")
+	ctx = context.WithValue(ctx, contexts.KeyRegion, "eu")
+	tags := map[string]string{"status": "ok"}
+	if err := r.IncWithTags(ctx, "requests_total", 2, tags); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.IncWithTags(context.Background(), "requests_total", 1, tags); err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 {
+		t.Fatalf("caller tags were modified: %v", tags)
+	}
+
+	got := strings.Join(scrape(t, r, "svc_requests_total"), "\n")
+	for _, want := range []string{
+		`svc_requests_total{region="eu",status="ok",tenant_id="t1"} 2`,
+		`svc_requests_total{region="",status="ok",tenant_id=""} 1`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s in\n%s", want, got)
+		}
+	}
+}
+
+func TestCounterRejectsNegativeDelta(t *testing.T) {
+	if err := New().Inc(context.Background(), "n", -1); err == nil {
+		t.Fatal("Inc with a negative delta succeeded")
+	}
+}
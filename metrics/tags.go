@@ -0,0 +1,70 @@
+package metrics
+
+import "sync"
+
+// TagSanitizer rewrites a tag value, or drops the tag by returning false.
+type TagSanitizer func(key, value string) (string, bool)
+
+// WithTagSanitizer runs fn over every tag of every point (default, extra,
+// per-call and context tags) before it is written. Sanitizers run in the
+// order they are added.
+func WithTagSanitizer(fn TagSanitizer) Option {
+	return func(c *Config) { c.TagSanitizers = append(c.TagSanitizers, fn) }
+}
+
+// DropTags removes the given tag keys.
+func DropTags(keys ...string) TagSanitizer {
+	drop := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		drop[k] = struct{}{}
+	}
+	return func(key, value string) (string, bool) {
+		_, ok := drop[key]
+		return value, !ok
+	}
+}
+
+// LimitTagValues keeps at most maxDistinct values per tag key and reports any
+// further value as "other". With keys it only applies to those tags.
+func LimitTagValues(maxDistinct int, keys ...string) TagSanitizer {
+	only := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		only[k] = struct{}{}
+	}
+	var mu sync.Mutex
+	seen := map[string]map[string]struct{}{}
+	return func(key, value string) (string, bool) {
+		if len(only) > 0 {
+			if _, ok := only[key]; !ok {
+				return value, true
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		vals, ok := seen[key]
+		if !ok {
+			vals = map[string]struct{}{}
+			seen[key] = vals
+		}
+		if _, ok := vals[value]; ok {
+			return value, true
+		}
+		if len(vals) >= maxDistinct {
+			return "other", true
+		}
+		vals[value] = struct{}{}
+		return value, true
+	}
+}
+
+func (c *Client) sanitize(tags map[string]string) {
+	for _, fn := range c.cfg.TagSanitizers {
+		for k, v := range tags {
+			if nv, keep := fn(k, v); !keep {
+				delete(tags, k)
+			} else {
+				tags[k] = nv
+			}
+		}
+	}
+}
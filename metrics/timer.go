@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Timer measures one duration and reports it in seconds via ObserveWithTags.
+//
+//	t := metrics.StartTimer(rec, "job_duration_seconds", tags)
+//	defer t.ObserveDuration(ctx)
+type Timer struct {
+	rec   Recorder
+	name  string
+	start time.Time
+
+	mu   sync.Mutex
+	tags map[string]string
+}
+
+// StartTimer starts timing name; tags is copied. A nil rec discards the result.
+func StartTimer(rec Recorder, name string, tags map[string]string) *Timer {
+	if rec == nil {
+		rec = NoOp()
+	}
+	t := &Timer{rec: rec, name: name, start: time.Now(), tags: make(map[string]string, len(tags)+1)}
+	for k, v := range tags {
+		t.tags[k] = v
+	}
+	return t
+}
+
+// SetTag adds or replaces a tag reported with the observation, e.g. the
+// outcome known only at the end.
+func (t *Timer) SetTag(key, value string) *Timer {
+	t.mu.Lock()
+	t.tags[key] = value
+	t.mu.Unlock()
+	return t
+}
+
+// Elapsed returns the time since the timer started.
+func (t *Timer) Elapsed() time.Duration { return time.Since(t.start) }
+
+// ObserveDuration records the elapsed seconds with the timer's tags.
+func (t *Timer) ObserveDuration(ctx context.Context) error {
+	t.mu.Lock()
+	tags := make(map[string]string, len(t.tags))
+	for k, v := range t.tags {
+		tags[k] = v
+	}
+	t.mu.Unlock()
+	return t.rec.ObserveWithTags(ctx, t.name, t.Elapsed().Seconds(), tags)
+}
@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/shadowofcards/go-toolkit/contexts"
+	apperrors "github.com/shadowofcards/go-toolkit/errors"
+)
+
+const APIVersionHeader = "X-API-Version"
+
+var ErrUnsupportedAPIVersion = apperrors.Define("UNSUPPORTED_API_VERSION", http.StatusBadRequest, "unsupported api version")
+
+// APIVersion negotiates the X-API-Version header against a fixed set of
+// supported versions and stores the result under contexts.KeyAPIVersion.
+type APIVersion struct {
+	supported []string
+	fallback  string
+}
+
+type APIVersionOption func(*APIVersion)
+
+// WithDefaultVersion sets the version used when the header is absent.
+func WithDefaultVersion(version string) APIVersionOption {
+	return func(v *APIVersion) {
+		v.fallback = version
+	}
+}
+
+// NewAPIVersion accepts the given versions. Requests without the header get the
+// first supported version unless WithDefaultVersion says otherwise. It panics
+// when supported is empty, since every versioned request would be rejected.
+func NewAPIVersion(supported []string, opts ...APIVersionOption) *APIVersion {
+	if len(supported) == 0 {
+		panic("NewAPIVersion: no supported versions")
+	}
+	v := &APIVersion{supported: supported, fallback: supported[0]}
+	for _, o := range opts {
+		o(v)
+	}
+	return v
+}
+
+func (v *APIVersion) Handler() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		version := strings.TrimSpace(c.Get(APIVersionHeader))
+		if version == "" {
+			version = v.fallback
+		} else if !v.isSupported(version) {
+			return ErrUnsupportedAPIVersion.
+				WithContext("version", version).
+				WithContext("supported", v.supported)
+		}
+
+		c.SetContext(context.WithValue(c.Context(), contexts.KeyAPIVersion, version))
+		c.Set(APIVersionHeader, version)
+		return c.Next()
+	}
+}
+
+func (v *APIVersion) isSupported(version string) bool {
+	for _, s := range v.supported {
+		if s == version {
+			return true
+		}
+	}
+	return false
+}
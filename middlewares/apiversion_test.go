@@ -0,0 +1,71 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	apperrors "github.com/shadowofcards/go-toolkit/errors"
+	"github.com/shadowofcards/go-toolkit/utils"
+)
+
+// versionApp serves GET / echoing the negotiated version in the body.
+func versionApp(v *APIVersion) *fiber.App {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c fiber.Ctx, err error) error {
+			if ae, ok := apperrors.FromError(err); ok {
+				return c.SendStatus(ae.Status())
+			}
+			return c.SendStatus(http.StatusInternalServerError)
+		},
+	})
+	app.Get("/", func(c fiber.Ctx) error { return c.SendString(utils.APIVersion(c)) }, v.Handler())
+	return app
+}
+
+func TestAPIVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []APIVersionOption
+		header string
+		status int
+		want   string
+	}{
+		{"header absent uses first", nil, "", http.StatusOK, "v1"},
+		{"header absent uses default", []APIVersionOption{WithDefaultVersion("v2")}, "", http.StatusOK, "v2"},
+		{"supported header", nil, "v2", http.StatusOK, "v2"},
+		{"unsupported header", nil, "v3", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := versionApp(NewAPIVersion([]string{"v1", "v2"}, tt.opts...))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(APIVersionHeader, tt.header)
+			}
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.status)
+			}
+			if body, _ := io.ReadAll(res.Body); tt.want != "" && string(body) != tt.want {
+				t.Fatalf("negotiated version = %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAPIVersionRequiresVersions(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewAPIVersion with no versions did not panic")
+		}
+	}()
+	NewAPIVersion(nil)
+}
@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/shadowofcards/go-toolkit/contexts"
+)
+
+// Caller headers:
+//
+//	X-Caller-ID     the service that made this call (set by httpclient from its
+//	                app name); "external" when absent.
+//	X-Caller-Chain  comma-separated list of the services the request has passed
+//	                through, oldest first, ending with the direct caller.
+const (
+	CallerIDHeader    = "X-Caller-ID"
+	CallerChainHeader = "X-Caller-Chain"
+)
+
+// NewCallerChain stores the direct caller under contexts.KeyCallerID and the
+// caller chain extended with appName under contexts.KeyCallerChain, so
+// httpclient forwards it on the next hop.
+func NewCallerChain(appName string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		caller := c.Get(CallerIDHeader, "external")
+
+		var chain []string
+		for _, s := range strings.Split(c.Get(CallerChainHeader), ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				chain = append(chain, s)
+			}
+		}
+		if appName != "" {
+			chain = append(chain, appName)
+		}
+
+		ctx := context.WithValue(c.Context(), contexts.KeyCallerID, caller)
+		ctx = context.WithValue(ctx, contexts.KeyCallerChain, strings.Join(chain, ","))
+		c.SetContext(ctx)
+		return c.Next()
+	}
+}
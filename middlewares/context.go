@@ -11,47 +11,25 @@ import (
 	gjwt "github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
 
-	"github.com/shadowofcards/go-toolkit/contexts"
 	apperrors "github.com/shadowofcards/go-toolkit/errors"
 	"github.com/shadowofcards/go-toolkit/jwt"
 	"github.com/shadowofcards/go-toolkit/logging"
 )
 
 var (
-	ErrMissingOrMalformedToken = apperrors.New().
-					WithHTTPStatus(http.StatusUnauthorized).
-					WithCode("MISSING_OR_MALFORMED_TOKEN").
-					WithMessage("missing or malformed token")
-
-	ErrTokenMalformed = apperrors.New().
-				WithHTTPStatus(http.StatusBadRequest).
-				WithCode("TOKEN_MALFORMED").
-				WithMessage("token is malformed")
-
-	ErrTokenUnverifiable = apperrors.New().
-				WithHTTPStatus(http.StatusBadRequest).
-				WithCode("TOKEN_UNVERIFIABLE").
-				WithMessage("token could not be verified")
-
-	ErrInvalidSignature = apperrors.New().
-				WithHTTPStatus(http.StatusUnauthorized).
-				WithCode("INVALID_SIGNATURE").
-				WithMessage("token signature is invalid")
-
-	ErrTokenExpired = apperrors.New().
-			WithHTTPStatus(http.StatusUnauthorized).
-			WithCode("TOKEN_EXPIRED").
-			WithMessage("token is expired")
-
-	ErrInvalidToken = apperrors.New().
-			WithHTTPStatus(http.StatusUnauthorized).
-			WithCode("INVALID_TOKEN").
-			WithMessage("invalid token")
-
-	ErrInvalidServiceToken = apperrors.New().
-				WithHTTPStatus(http.StatusUnauthorized).
-				WithCode("INVALID_SERVICE_TOKEN").
-				WithMessage("invalid service token")
+	ErrMissingOrMalformedToken = apperrors.Define("MISSING_OR_MALFORMED_TOKEN", http.StatusUnauthorized, "missing or malformed token")
+
+	ErrTokenMalformed = apperrors.Define("TOKEN_MALFORMED", http.StatusBadRequest, "token is malformed")
+
+	ErrTokenUnverifiable = apperrors.Define("TOKEN_UNVERIFIABLE", http.StatusBadRequest, "token could not be verified")
+
+	ErrInvalidSignature = apperrors.Define("INVALID_SIGNATURE", http.StatusUnauthorized, "token signature is invalid")
+
+	ErrTokenExpired = apperrors.Define("TOKEN_EXPIRED", http.StatusUnauthorized, "token is expired")
+
+	ErrInvalidToken = apperrors.Define("INVALID_TOKEN", http.StatusUnauthorized, "invalid token")
+
+	ErrInvalidServiceToken = apperrors.Define("INVALID_SERVICE_TOKEN", http.StatusUnauthorized, "invalid service token")
 )
 
 type AuthMiddleware struct {
@@ -89,91 +67,81 @@ func (a *AuthMiddleware) Handler() fiber.Handler {
 }
 
 func injectTrace(c fiber.Ctx) context.Context {
-	ctx := c.Context()
 	rid := c.Get("X-Request-Id")
 	if rid == "" {
 		rid = requestid.FromContext(c)
 	}
-	ctx = context.WithValue(ctx, contexts.KeyRequestID, rid)
-	if v := c.Get("X-App-Name"); v != "" {
-		ctx = context.WithValue(ctx, contexts.KeyOrigin, v)
-	}
-	if v := c.Get("X-User-Agent"); v != "" {
-		ctx = context.WithValue(ctx, contexts.KeyUserAgent, v)
-	}
+	ctx := traceContext(c.Context(), rid, c.Get)
 	c.SetContext(ctx)
 	return ctx
 }
 
 func (a *AuthMiddleware) authenticateService(ctx context.Context, c fiber.Ctx, token string) error {
-	if token != a.serviceToken {
-		return ErrInvalidServiceToken
+	id, err := a.verifyServiceToken(token)
+	if err != nil {
+		return err
 	}
-	ctx = context.WithValue(ctx, contexts.KeyUserID, a.appName)
-	ctx = context.WithValue(ctx, contexts.KeyUsername, a.appName)
-	ctx = context.WithValue(ctx, contexts.KeyUserRoles, []string{"service"})
+	ctx = id.Inject(ctx)
 	c.SetContext(ctx)
-	c.Locals("roles", []string{"service"})
+	c.Locals("roles", id.Roles)
 	a.log.InfoCtx(ctx, "service token authenticated", zap.String("service", a.appName))
 	return c.Next()
 }
 
 func (a *AuthMiddleware) authenticateJWT(ctx context.Context, c fiber.Ctx) error {
-	header := c.Get("Authorization")
+	id, mClaims, err := a.verifyBearer(ctx, c.Get("Authorization"))
+	if err != nil {
+		return err
+	}
+
+	ctx = id.Inject(ctx)
+	c.SetContext(ctx)
+
+	c.Locals("claims", mClaims)
+	c.Locals("tenantID", id.TenantID)
+	c.Locals("userID", id.UserID)
+	c.Locals("username", id.Username)
+	c.Locals("roles", id.Roles)
+
+	a.log.InfoCtx(ctx, "jwt authenticated",
+		zap.String("tenant", id.TenantID),
+		zap.String("user", id.UserID),
+		zap.Strings("roles", id.Roles),
+	)
+	return c.Next()
+}
+
+func (a *AuthMiddleware) verifyServiceToken(token string) (Identity, error) {
+	if token != a.serviceToken {
+		return Identity{}, ErrInvalidServiceToken
+	}
+	return ServiceIdentity(a.appName), nil
+}
+
+// verifyBearer validates an "Authorization: Bearer" header value and maps
+// verifier failures to the middleware's public errors.
+func (a *AuthMiddleware) verifyBearer(ctx context.Context, header string) (Identity, gjwt.MapClaims, error) {
 	if !strings.HasPrefix(header, "Bearer ") {
-		return ErrMissingOrMalformedToken
+		return Identity{}, nil, ErrMissingOrMalformedToken
 	}
 	tokenStr := strings.TrimPrefix(header, "Bearer ")
 
-	var mClaims gjwt.MapClaims
 	allowExpired := a.env != "production"
-	if err := a.verifier.Validate(ctx, tokenStr, &mClaims, allowExpired); err != nil {
+	claims, err := a.verifier.Parse(ctx, tokenStr, jwt.AllowExpired(allowExpired))
+	if err != nil {
 		a.log.ErrorCtx(ctx, "jwt validation failed", zap.Error(err))
 		switch {
 		case errors.Is(err, gjwt.ErrTokenMalformed):
-			return ErrTokenMalformed
+			return Identity{}, nil, ErrTokenMalformed
 		case errors.Is(err, gjwt.ErrTokenUnverifiable):
-			return ErrTokenUnverifiable
+			return Identity{}, nil, ErrTokenUnverifiable
 		case errors.Is(err, gjwt.ErrTokenSignatureInvalid):
-			return ErrInvalidSignature
+			return Identity{}, nil, ErrInvalidSignature
 		case errors.Is(err, gjwt.ErrTokenExpired):
-			return ErrTokenExpired
+			return Identity{}, nil, ErrTokenExpired
 		default:
-			return ErrInvalidToken
-		}
-	}
-
-	sub, _ := mClaims["sub"].(string)
-	tid, _ := mClaims["tid"].(string)
-	usern, _ := mClaims["preferred_username"].(string)
-
-	var roles []string
-	if ra, ok := mClaims["realm_access"].(map[string]interface{}); ok {
-		if arr, ok := ra["roles"].([]interface{}); ok {
-			for _, r := range arr {
-				if s, ok := r.(string); ok {
-					roles = append(roles, s)
-				}
-			}
+			return Identity{}, nil, ErrInvalidToken
 		}
 	}
-
-	ctx = context.WithValue(ctx, contexts.KeyTenantID, tid)
-	ctx = context.WithValue(ctx, contexts.KeyUserID, sub)
-	ctx = context.WithValue(ctx, contexts.KeyUsername, usern)
-	ctx = context.WithValue(ctx, contexts.KeyUserRoles, roles)
-	c.SetContext(ctx)
-
-	c.Locals("claims", mClaims)
-	c.Locals("tenantID", tid)
-	c.Locals("userID", sub)
-	c.Locals("username", usern)
-	c.Locals("roles", roles)
-
-	a.log.InfoCtx(ctx, "jwt authenticated",
-		zap.String("tenant", tid),
-		zap.String("user", sub),
-		zap.Strings("roles", roles),
-	)
-	return c.Next()
+	return identityFrom(claims), claims.Raw, nil
 }
@@ -1,13 +1,16 @@
 package middlewares
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"strings"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v3"
 	apperr "github.com/shadowofcards/go-toolkit/errors"
+	"github.com/shadowofcards/go-toolkit/logging"
 	"github.com/shadowofcards/go-toolkit/metrics"
+	"go.uber.org/zap"
 )
 
 type errorPayload struct {
@@ -16,7 +19,58 @@ type errorPayload struct {
 	Context interface{} `json:"context,omitempty"`
 }
 
-func NewErrorHandler(rec metrics.Recorder) fiber.ErrorHandler {
+// ErrorHandlerOption configures NewErrorHandler.
+type ErrorHandlerOption func(*errorHandlerCfg)
+
+type errorHandlerCfg struct {
+	problemJSON bool
+	typePrefix  string
+	log         *logging.Logger
+}
+
+// WithErrorLogging logs 5xx responses with the error, its internal context and,
+// when captured (see errors.CaptureStack), its stack trace. None of these are
+// sent to the client.
+func WithErrorLogging(l *logging.Logger) ErrorHandlerOption {
+	return func(c *errorHandlerCfg) { c.log = l }
+}
+
+func (cfg *errorHandlerCfg) logServerError(ctx context.Context, status int, err error) {
+	if cfg.log == nil || status < http.StatusInternalServerError {
+		return
+	}
+	fields := []zap.Field{zap.Int("status", status), zap.Error(err)}
+	if ae, ok := apperr.FromError(err); ok {
+		if len(ae.Internal) > 0 {
+			fields = append(fields, zap.Any("internal", ae.Internal))
+		}
+		if st := ae.StackTrace(); len(st) > 0 {
+			fields = append(fields, zap.String("stack", apperr.FormatStack(st)))
+		}
+	}
+	cfg.log.ErrorCtx(ctx, "request failed", fields...)
+}
+
+// WithProblemJSON answers clients sending Accept: application/problem+json with
+// an RFC 7807 body. The error code becomes the type URN (typePrefix + code,
+// default "urn:error:") and the message the detail. Other clients keep the
+// {"error":{...}} shape.
+func WithProblemJSON(typePrefix string) ErrorHandlerOption {
+	return func(c *errorHandlerCfg) {
+		if typePrefix == "" {
+			typePrefix = "urn:error:"
+		}
+		c.problemJSON, c.typePrefix = true, typePrefix
+	}
+}
+
+func NewErrorHandler(rec metrics.Recorder, opts ...ErrorHandlerOption) fiber.ErrorHandler {
+	cfg := &errorHandlerCfg{}
+	for _, o := range opts {
+		o(cfg)
+	}
+	respond := cfg.respond
+
 	return func(c fiber.Ctx, err error) error {
 		ctx := c.Context()
 
@@ -33,23 +87,34 @@ func NewErrorHandler(rec metrics.Recorder) fiber.ErrorHandler {
 			"caller": caller,
 		}
 
-		var ve validator.ValidationErrors
-		if errors.As(err, &ve) {
-			tags["code"] = "VALIDATION_ERROR"
+		if ve := apperr.FromValidation(err); ve != nil {
+			tags["code"] = ve.ErrCode()
 			tags["type"] = "validation"
 			_ = rec.IncWithTags(ctx, "http_errors_total", 1, tags)
 
-			context := make(map[string]string, len(ve))
-			for _, f := range ve {
-				context[f.Field()] = "validation failed on '" + f.Tag() + "'"
-			}
-			return respond(c, http.StatusBadRequest, errorPayload{
-				Code:    "VALIDATION_ERROR",
-				Message: "validation failed",
-				Context: context,
+			return respond(c, ve.Status(), errorPayload{
+				Code:    ve.ErrCode(),
+				Message: ve.Message,
+				Context: ve.Context,
 			})
 		}
 
+		var multi *apperr.Multi
+		if errors.As(err, &multi) && len(multi.Errors) > 0 {
+			tags["code"] = "MULTIPLE_ERRORS"
+			tags["type"] = "multi_error"
+			_ = rec.IncWithTags(ctx, "http_errors_total", 1, tags)
+
+			for _, e := range multi.Errors {
+				cfg.logServerError(ctx, e.Status(), e)
+			}
+			return cfg.respondBody(c, multi.Status(), errorPayload{
+				Code:    "MULTIPLE_ERRORS",
+				Message: "multiple errors occurred",
+				Context: fiber.Map{"errors": multi},
+			}, fiber.Map{"errors": multi})
+		}
+
 		if ae, ok := apperr.FromError(err); ok {
 			tags["code"] = ae.ErrCode()
 			tags["type"] = "app_error"
@@ -62,6 +127,7 @@ func NewErrorHandler(rec metrics.Recorder) fiber.ErrorHandler {
 			if len(ae.Context) > 0 {
 				payload.Context = ae.Context
 			}
+			cfg.logServerError(ctx, ae.Status(), err)
 			return respond(c, ae.Status(), payload)
 		}
 
@@ -79,6 +145,7 @@ func NewErrorHandler(rec metrics.Recorder) fiber.ErrorHandler {
 		tags["code"] = "INTERNAL_ERROR"
 		tags["type"] = "unknown"
 		_ = rec.IncWithTags(ctx, "http_errors_total", 1, tags)
+		cfg.logServerError(ctx, http.StatusInternalServerError, err)
 
 		return respond(c, http.StatusInternalServerError, errorPayload{
 			Code:    "INTERNAL_ERROR",
@@ -87,6 +154,30 @@ func NewErrorHandler(rec metrics.Recorder) fiber.ErrorHandler {
 	}
 }
 
-func respond(c fiber.Ctx, status int, p errorPayload) error {
-	return c.Status(status).JSON(fiber.Map{"error": p})
+type problemPayload struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Context  interface{} `json:"context,omitempty"`
+}
+
+func (cfg *errorHandlerCfg) respond(c fiber.Ctx, status int, p errorPayload) error {
+	return cfg.respondBody(c, status, p, fiber.Map{"error": p})
+}
+
+// respondBody sends p as problem+json when negotiated, and body otherwise.
+func (cfg *errorHandlerCfg) respondBody(c fiber.Ctx, status int, p errorPayload, body any) error {
+	if cfg.problemJSON && strings.Contains(c.Get(fiber.HeaderAccept), "application/problem+json") {
+		return c.Status(status).JSON(problemPayload{
+			Type:     cfg.typePrefix + p.Code,
+			Title:    http.StatusText(status),
+			Status:   status,
+			Detail:   p.Message,
+			Instance: c.OriginalURL(),
+			Context:  p.Context,
+		}, "application/problem+json")
+	}
+	return c.Status(status).JSON(body)
 }
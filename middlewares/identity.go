@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"context"
+
+	gjwt "github.com/golang-jwt/jwt/v5"
+
+	"github.com/shadowofcards/go-toolkit/contexts"
+	"github.com/shadowofcards/go-toolkit/jwt"
+)
+
+// Identity is the caller identity resolved by the auth middlewares. It is
+// framework-agnostic so the Fiber and net/http adapters share one code path.
+type Identity struct {
+	TenantID string
+	UserID   string
+	Username string
+	Roles    []string
+
+	service bool
+}
+
+// IdentityFromClaims extracts the identity from Keycloak-style claims
+// (sub, tid, preferred_username, realm_access.roles).
+func IdentityFromClaims(claims gjwt.MapClaims) Identity {
+	return identityFrom(jwt.ClaimsFromMap(claims))
+}
+
+func identityFrom(c *jwt.Claims) Identity {
+	return Identity{
+		TenantID: c.TenantID,
+		UserID:   c.Subject,
+		Username: c.Username,
+		Roles:    c.Roles,
+	}
+}
+
+// ServiceIdentity is the identity assigned to callers presenting the service token.
+func ServiceIdentity(appName string) Identity {
+	return Identity{
+		UserID:   appName,
+		Username: appName,
+		Roles:    []string{"service"},
+		service:  true,
+	}
+}
+
+// Inject stores the identity in ctx under the contexts keys. KeyTenantID is
+// always set for token identities, even when empty, and never for the service
+// identity, so a tenant already in ctx is kept for service calls.
+func (i Identity) Inject(ctx context.Context) context.Context {
+	if !i.service {
+		ctx = context.WithValue(ctx, contexts.KeyTenantID, i.TenantID)
+	}
+	ctx = context.WithValue(ctx, contexts.KeyUserID, i.UserID)
+	ctx = context.WithValue(ctx, contexts.KeyUsername, i.Username)
+	ctx = context.WithValue(ctx, contexts.KeyUserRoles, i.Roles)
+	return ctx
+}
+
+// traceContext stores the request id and the caller headers forwarded by
+// httpclient (X-App-Name, X-User-Agent) in ctx.
+func traceContext(ctx context.Context, rid string, header func(key string, defaultValue ...string) string) context.Context {
+	ctx = context.WithValue(ctx, contexts.KeyRequestID, rid)
+	if v := header("X-App-Name"); v != "" {
+		ctx = context.WithValue(ctx, contexts.KeyOrigin, v)
+	}
+	if v := header("X-User-Agent"); v != "" {
+		ctx = context.WithValue(ctx, contexts.KeyUserAgent, v)
+	}
+	return ctx
+}
@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// defaultIntrospectionCacheSize caps the number of tokens WithIntrospectionCache
+// remembers unless WithIntrospectionCacheSize says otherwise.
+const defaultIntrospectionCacheSize = 10000
+
+type introspectionEntry struct {
+	key     string
+	result  map[string]interface{}
+	expires time.Time
+}
+
+// introspectionCache is a size-bounded LRU of successful introspection
+// results keyed by the token hash.
+type introspectionCache struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	max   int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// WithIntrospectionCache caches successful introspection results for ttl, or
+// until the token's exp if that comes first, so repeat connections with the
+// same token skip the IdP. Hits and misses are counted by ws_introspection_cache.
+func WithIntrospectionCache(ttl time.Duration) WSAuthOption {
+	return func(m *WSAuthMiddleware) {
+		m.introspectionCache = &introspectionCache{
+			ttl:   ttl,
+			max:   defaultIntrospectionCacheSize,
+			ll:    list.New(),
+			items: map[string]*list.Element{},
+		}
+	}
+}
+
+// WithIntrospectionCacheSize bounds the introspection cache to maxEntries
+// tokens (default 10000). It has no effect without WithIntrospectionCache.
+func WithIntrospectionCacheSize(maxEntries int) WSAuthOption {
+	return func(m *WSAuthMiddleware) {
+		m.introspectionCacheSize = maxEntries
+	}
+}
+
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *introspectionCache) get(key string) (map[string]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*introspectionEntry)
+	if time.Now().After(e.expires) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return e.result, true
+}
+
+func (c *introspectionCache) put(key string, result map[string]interface{}) {
+	exp := time.Now().Add(c.ttl)
+	if v, ok := result["exp"].(float64); ok {
+		if tokenExp := time.Unix(int64(v), 0); tokenExp.Before(exp) {
+			exp = tokenExp
+		}
+	}
+	if !exp.After(time.Now()) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*introspectionEntry)
+		e.result, e.expires = result, exp
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&introspectionEntry{key: key, result: result, expires: exp})
+	for c.ll.Len() > c.max {
+		old := c.ll.Back()
+		c.ll.Remove(old)
+		delete(c.items, old.Value.(*introspectionEntry).key)
+	}
+}
+
+// introspect calls the introspector, consulting the cache when configured.
+func (a *WSAuthMiddleware) introspect(ctx context.Context, token string) (map[string]interface{}, error) {
+	if a.introspectionCache == nil {
+		return a.introspector.Introspect(ctx, token)
+	}
+	key := tokenKey(token)
+	if res, ok := a.introspectionCache.get(key); ok {
+		a.rec.IncWithTags(ctx, "ws_introspection_cache", 1, map[string]string{"result": "hit", "app": a.appName})
+		return res, nil
+	}
+	a.rec.IncWithTags(ctx, "ws_introspection_cache", 1, map[string]string{"result": "miss", "app": a.appName})
+	res, err := a.introspector.Introspect(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	a.introspectionCache.put(key, res)
+	return res, nil
+}
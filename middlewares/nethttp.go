@@ -0,0 +1,197 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shadowofcards/go-toolkit/contexts"
+	apperr "github.com/shadowofcards/go-toolkit/errors"
+	"github.com/shadowofcards/go-toolkit/metrics"
+)
+
+/*──────────────────────────────
+   net/http adapters
+──────────────────────────────*/
+
+// HTTPHandler is the net/http (chi, std mux) equivalent of Handler. It shares
+// token verification and identity extraction with the Fiber middleware and
+// answers failures with the same {"error":{...}} body as NewErrorHandler.
+func (a *AuthMiddleware) HTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rid := r.Header.Get("X-Request-Id")
+		if rid == "" {
+			rid = uuid.NewString()
+		}
+		ctx := traceContext(r.Context(), rid, headerGetter(r.Header))
+
+		var id Identity
+		var err error
+		if token := r.Header.Get("X-Service-Token"); token != "" {
+			id, err = a.verifyServiceToken(token)
+		} else {
+			id, _, err = a.verifyBearer(ctx, r.Header.Get("Authorization"))
+		}
+		if err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+
+		ctx = id.Inject(ctx)
+		a.log.InfoCtx(ctx, "request authenticated",
+			zap.String("tenant", id.TenantID),
+			zap.String("user", id.UserID),
+			zap.Strings("roles", id.Roles),
+		)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// HTTPHandler is the net/http equivalent of Handler.
+func (l *Logger) HTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		start := time.Now()
+		rid, _ := ctx.Value(contexts.KeyRequestID).(string)
+		if rid == "" {
+			rid = r.Header.Get("X-Request-Id")
+		}
+
+		fields := []zap.Field{
+			zap.String("request_id", rid),
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+		}
+		if tid, ok := ctx.Value(contexts.KeyTenantID).(string); ok && tid != "" {
+			fields = append(fields, zap.String("tenant_id", tid))
+		}
+		if uid, ok := ctx.Value(contexts.KeyUserID).(string); ok && uid != "" {
+			fields = append(fields, zap.String("user_id", uid))
+		}
+		if l.logQuery {
+			fields = append(fields, zap.String("query", sanitizeQuery(r.URL.RawQuery, l.queryBlacklist)))
+		}
+		if l.logHeaders {
+			fields = append(fields, zap.Any("headers", collectHTTPHeaders(r.Header, l.headerWhitelist)))
+		}
+		l.log.InfoCtx(ctx, "request received", fields...)
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		l.log.InfoCtx(ctx, "response sent",
+			zap.String("request_id", rid),
+			zap.Int("status", sw.Status()),
+			zap.Duration("latency", time.Since(start)),
+		)
+	})
+}
+
+// HTTPMetrics is the net/http equivalent of WithHTTPMetrics.
+func HTTPMetrics(rec metrics.Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx := r.Context()
+
+			caller := r.Header.Get("X-Caller-ID")
+			if caller == "" {
+				caller = "external"
+			}
+			tags := map[string]string{
+				"path":   normalizePath(r.URL.Path),
+				"method": r.Method,
+				"caller": caller,
+			}
+
+			inFlight := maps.Clone(tags)
+			_ = metrics.AddGauge(ctx, rec, "http_in_flight_requests", 1, inFlight)
+
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+
+			tags["status"] = statusCodeKey(sw.Status())
+			_ = metrics.AddGauge(ctx, rec, "http_in_flight_requests", -1, inFlight)
+			_ = rec.IncWithTags(ctx, "http_requests_total", 1, tags)
+			_ = rec.ObserveWithTags(ctx, "http_request_duration_seconds", time.Since(start).Seconds(), tags)
+		})
+	}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func headerGetter(h http.Header) func(string, ...string) string {
+	return func(key string, defaultValue ...string) string {
+		if v := h.Get(key); v != "" {
+			return v
+		}
+		if len(defaultValue) > 0 {
+			return defaultValue[0]
+		}
+		return ""
+	}
+}
+
+func collectHTTPHeaders(h http.Header, whitelist []string) map[string]string {
+	out := make(map[string]string)
+	if len(whitelist) > 0 {
+		for _, key := range whitelist {
+			if v := h.Get(key); v != "" {
+				out[key] = v
+			}
+		}
+		return out
+	}
+	for k := range h {
+		if http.CanonicalHeaderKey(k) == "Authorization" {
+			continue
+		}
+		out[k] = h.Get(k)
+	}
+	return out
+}
+
+func writeHTTPError(w http.ResponseWriter, err error) {
+	payload := errorPayload{Code: "INTERNAL_ERROR", Message: "internal server error"}
+	status := http.StatusInternalServerError
+	if ae, ok := apperr.FromError(err); ok {
+		payload = errorPayload{Code: ae.ErrCode(), Message: ae.Message}
+		if len(ae.Context) > 0 {
+			payload.Context = ae.Context
+		}
+		status = ae.Status()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": payload})
+}
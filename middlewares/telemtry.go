@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"maps"
 	"net/http"
 	"regexp"
 	"strings"
@@ -45,7 +46,8 @@ func WithHTTPMetrics(rec metrics.Recorder) fiber.Handler {
 			"caller": caller,
 		}
 
-		_ = rec.GaugeWithTags(ctx, "http_in_flight_requests", 1, tags)
+		inFlight := maps.Clone(tags)
+		_ = metrics.AddGauge(ctx, rec, "http_in_flight_requests", 1, inFlight)
 
 		err := c.Next()
 
@@ -53,7 +55,7 @@ func WithHTTPMetrics(rec metrics.Recorder) fiber.Handler {
 		status := c.Response().StatusCode()
 		tags["status"] = statusCodeKey(status)
 
-		_ = rec.GaugeWithTags(ctx, "http_in_flight_requests", -1, tags)
+		_ = metrics.AddGauge(ctx, rec, "http_in_flight_requests", -1, inFlight)
 		_ = rec.IncWithTags(ctx, "http_requests_total", 1, tags)
 		_ = rec.ObserveWithTags(ctx, "http_request_duration_seconds", duration, tags)
 
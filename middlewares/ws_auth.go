@@ -7,7 +7,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/shadowofcards/go-toolkit/contexts"
 	apperr "github.com/shadowofcards/go-toolkit/errors"
 	gtkjwt "github.com/shadowofcards/go-toolkit/jwt"
@@ -18,22 +17,13 @@ import (
 )
 
 var (
-	ErrMissingToken      = apperr.New().WithHTTPStatus(http.StatusUnauthorized).WithCode("MISSING_TOKEN").WithMessage("missing token")
-	ErrTokenExpiredByAge = apperr.New().WithHTTPStatus(http.StatusUnauthorized).WithCode("TOKEN_EXPIRED").WithMessage("token too old")
-	ErrMissingClaim      = apperr.New().WithHTTPStatus(http.StatusUnauthorized).WithCode("MISSING_CLAIM").WithMessage("no subject or player_id in token")
+	ErrMissingToken      = apperr.Define("MISSING_TOKEN", http.StatusUnauthorized, "missing token")
+	ErrTokenExpiredByAge = apperr.Define("TOKEN_EXPIRED", http.StatusUnauthorized, "token too old")
+	ErrMissingClaim      = apperr.Define("MISSING_CLAIM", http.StatusUnauthorized, "no subject or player_id in token")
+	ErrIdentityMismatch  = apperr.Define("IDENTITY_MISMATCH", http.StatusForbidden, "token belongs to a different user")
+	ErrTokenRevoked      = apperr.Define("TOKEN_REVOKED", http.StatusUnauthorized, "token has been revoked")
 )
 
-type wsJWTClaims struct {
-	jwt.RegisteredClaims
-	PlayerID          string `json:"player_id"`
-	PreferredUsername string `json:"preferred_username"`
-	RealmAccess       struct {
-		Roles []string `json:"roles"`
-	} `json:"realm_access"`
-	Tid   string   `json:"tid"`
-	Perms []string `json:"perms"`
-}
-
 type TokenIntrospector interface {
 	Introspect(ctx context.Context, token string) (map[string]interface{}, error)
 }
@@ -52,6 +42,15 @@ func WithIntrospector(introspector TokenIntrospector) WSAuthOption {
 	}
 }
 
+// WithRevocationCutoff rejects tokens issued before cutoff(tenantID) with
+// TOKEN_REVOKED. A zero time means no cutoff; when one is set, tokens without
+// an iat claim are rejected too.
+func WithRevocationCutoff(cutoff func(tenantID string) time.Time) WSAuthOption {
+	return func(m *WSAuthMiddleware) {
+		m.revocationCutoff = cutoff
+	}
+}
+
 type WSAuthMiddleware struct {
 	log          *logging.Logger
 	verifier     *gtkjwt.Verifier
@@ -61,6 +60,12 @@ type WSAuthMiddleware struct {
 	env          string
 	maxTokenAge  time.Duration
 	rec          metrics.Recorder
+
+	revocationCutoff func(tenantID string) time.Time
+	extractors       []TokenExtractor
+
+	introspectionCache     *introspectionCache
+	introspectionCacheSize int
 }
 
 func NewWSAuthMiddleware(
@@ -79,10 +84,17 @@ func NewWSAuthMiddleware(
 		env:          env,
 		maxTokenAge:  maxTokenAge,
 		rec:          rec,
+		extractors:   []TokenExtractor{TokenFromQuery("token")},
+	}
+	if m.rec == nil {
+		m.rec = metrics.NoOp()
 	}
 	for _, o := range opts {
 		o(m)
 	}
+	if m.introspectionCache != nil && m.introspectionCacheSize > 0 {
+		m.introspectionCache.max = m.introspectionCacheSize
+	}
 	return m
 }
 
@@ -105,84 +117,118 @@ func (a *WSAuthMiddleware) Middleware() websocket.Middleware {
 				"app":       a.appName,
 			}
 
-			token := r.URL.Query().Get("token")
+			token := a.extractToken(r)
 			if token == "" {
 				tags["result"] = "missing_token"
 				a.rec.IncWithTags(ctx, "ws_auth_attempt_total", 1, tags)
 				writeError(w, ErrMissingToken)
-				a.log.WarnCtx(ctx, "missing token query parameter")
+				a.log.WarnCtx(ctx, "missing token on handshake")
 				return
 			}
 
-			if strings.EqualFold(token, a.serviceToken) {
-				tags["result"] = "service_token"
-				a.rec.IncWithTags(ctx, "ws_auth_attempt_total", 1, tags)
-				ctx = context.WithValue(ctx, contexts.KeyUserID, a.appName)
-				ctx = context.WithValue(ctx, contexts.KeyUsername, a.appName)
-				ctx = context.WithValue(ctx, contexts.KeyUserRoles, []string{"service"})
-				a.log.InfoCtx(ctx, "service token authenticated")
-				next(w, r.WithContext(ctx))
+			ctx, result, appErr := a.authenticate(ctx, token)
+			tags["result"] = result
+			if result == "success" {
+				tags["userid"], _ = ctx.Value(contexts.KeyUserID).(string)
+			}
+			a.rec.IncWithTags(ctx, "ws_auth_attempt_total", 1, tags)
+			if appErr != nil {
+				writeError(w, appErr)
 				return
 			}
 
-			if a.introspector != nil {
-				if _, err := a.introspector.Introspect(ctx, token); err != nil {
-					tags["result"] = "introspection_failed"
-					a.rec.IncWithTags(ctx, "ws_auth_attempt_total", 1, tags)
-					a.log.WarnCtx(ctx, "token introspection failed", zap.Error(err))
-					writeError(w, ErrInvalidToken)
-					return
-				}
-			}
+			next(w, r.WithContext(ctx))
+		}
+	}
+}
 
-			var claims wsJWTClaims
-			allowExpired := a.env != "production"
-			if err := a.verifier.Validate(ctx, token, &claims, allowExpired); err != nil {
-				tags["result"] = "jwt_invalid"
-				a.rec.IncWithTags(ctx, "ws_auth_attempt_total", 1, tags)
-				a.log.WarnCtx(ctx, "jwt validation failed", zap.Error(err))
-				writeError(w, ErrInvalidToken)
-				return
-			}
+// Reauthenticate validates a fresh token presented over an already established
+// connection, using the same rules as the handshake. The returned context carries
+// the refreshed identity; a token for a different user is rejected. It matches
+// websocket.Reauthenticator so it can be passed to websocket.WithReauthenticator.
+func (a *WSAuthMiddleware) Reauthenticate(ctx context.Context, token string) (context.Context, error) {
+	tags := map[string]string{
+		"stage": "reauth",
+		"app":   a.appName,
+	}
+	if token == "" {
+		tags["result"] = "missing_token"
+		a.rec.IncWithTags(ctx, "ws_auth_attempt_total", 1, tags)
+		a.log.WarnCtx(ctx, "missing token on reauthentication")
+		return ctx, ErrMissingToken
+	}
 
-			if a.maxTokenAge > 0 && claims.IssuedAt != nil {
-				age := time.Since(claims.IssuedAt.Time)
-				if age > a.maxTokenAge {
-					tags["result"] = "token_expired"
-					a.rec.IncWithTags(ctx, "ws_auth_attempt_total", 1, tags)
-					a.log.WarnCtx(ctx, "token expired by age")
-					writeError(w, ErrTokenExpiredByAge)
-					return
-				}
-			}
+	newCtx, result, appErr := a.authenticate(ctx, token)
+	if appErr == nil {
+		prev, _ := ctx.Value(contexts.KeyUserID).(string)
+		next, _ := newCtx.Value(contexts.KeyUserID).(string)
+		if prev != "" && prev != next {
+			result = "identity_mismatch"
+			appErr = ErrIdentityMismatch
+			a.log.WarnCtx(ctx, "reauthentication token belongs to another user", zap.String("user", next))
+		}
+	}
+	tags["result"] = result
+	a.rec.IncWithTags(ctx, "ws_auth_attempt_total", 1, tags)
+	if appErr != nil {
+		return ctx, appErr
+	}
+	a.log.InfoCtx(newCtx, "ws connection reauthenticated")
+	return newCtx, nil
+}
 
-			userID := claims.Subject
-			if userID == "" {
-				userID = claims.PlayerID
-			}
-			if userID == "" {
-				tags["result"] = "missing_claim"
-				a.rec.IncWithTags(ctx, "ws_auth_attempt_total", 1, tags)
-				writeError(w, ErrMissingClaim)
-				a.log.WarnCtx(ctx, "no subject or player_id in token")
-				return
-			}
+// authenticate validates token and returns ctx enriched with the caller identity,
+// along with the result tag used for ws_auth_attempt_total.
+func (a *WSAuthMiddleware) authenticate(ctx context.Context, token string) (context.Context, string, *apperr.AppError) {
+	if strings.EqualFold(token, a.serviceToken) {
+		ctx = ServiceIdentity(a.appName).Inject(ctx)
+		a.log.InfoCtx(ctx, "service token authenticated")
+		return ctx, "service_token", nil
+	}
 
-			roles := claims.RealmAccess.Roles
-			roles = append(roles, claims.Perms...)
+	if a.introspector != nil {
+		if _, err := a.introspect(ctx, token); err != nil {
+			a.log.WarnCtx(ctx, "token introspection failed", zap.Error(err))
+			return ctx, "introspection_failed", ErrInvalidToken
+		}
+	}
 
-			ctx = context.WithValue(ctx, contexts.KeyTenantID, claims.Tid)
-			ctx = context.WithValue(ctx, contexts.KeyUserID, userID)
-			ctx = context.WithValue(ctx, contexts.KeyUsername, claims.PreferredUsername)
-			ctx = context.WithValue(ctx, contexts.KeyUserRoles, roles)
+	allowExpired := a.env != "production"
+	claims, err := a.verifier.Parse(ctx, token, gtkjwt.AllowExpired(allowExpired))
+	if err != nil {
+		a.log.WarnCtx(ctx, "jwt validation failed", zap.Error(err))
+		return ctx, "jwt_invalid", ErrInvalidToken
+	}
 
-			tags["result"] = "success"
-			tags["userid"] = userID
-			a.rec.IncWithTags(ctx, "ws_auth_attempt_total", 1, tags)
+	if a.maxTokenAge > 0 && !claims.IssuedAt.IsZero() {
+		age := time.Since(claims.IssuedAt)
+		if age > a.maxTokenAge {
+			a.log.WarnCtx(ctx, "token expired by age")
+			return ctx, "token_expired", ErrTokenExpiredByAge
+		}
+	}
 
-			next(w, r.WithContext(ctx))
+	if a.revocationCutoff != nil {
+		if cutoff := a.revocationCutoff(claims.TenantID); !cutoff.IsZero() &&
+			(claims.IssuedAt.IsZero() || claims.IssuedAt.Before(cutoff)) {
+			a.log.WarnCtx(ctx, "token issued before revocation cutoff", zap.String("tenant", claims.TenantID))
+			return ctx, "token_revoked", ErrTokenRevoked
 		}
 	}
+
+	userID := claims.Subject
+	if userID == "" {
+		userID = claims.PlayerID
+	}
+	if userID == "" {
+		a.log.WarnCtx(ctx, "no subject or player_id in token")
+		return ctx, "missing_claim", ErrMissingClaim
+	}
+
+	id := identityFrom(claims)
+	id.UserID = userID
+	id.Roles = append(id.Roles, claims.Permissions...)
+	return id.Inject(ctx), "success", nil
 }
 
 func writeError(w http.ResponseWriter, appErr *apperr.AppError) {
@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+)
+
+// TokenExtractor returns the token carried by a handshake request, or "".
+type TokenExtractor func(r *http.Request) string
+
+// TokenFromQuery reads the token from the named query parameter. Query strings
+// end up in access logs and proxies; avoid this mode in production.
+func TokenFromQuery(param string) TokenExtractor {
+	return func(r *http.Request) string {
+		return r.URL.Query().Get(param)
+	}
+}
+
+// TokenFromAuthorization reads an "Authorization: Bearer <token>" header.
+func TokenFromAuthorization() TokenExtractor {
+	return func(r *http.Request) string {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	}
+}
+
+// TokenFromSubprotocol reads the token from a Sec-WebSocket-Protocol entry of
+// the form prefix+token (e.g. "bearer.<token>"), the only header browsers can
+// set on the handshake. The server must still negotiate another offered
+// subprotocol (see websocket.WithSubprotocols) for browsers to accept the
+// connection.
+func TokenFromSubprotocol(prefix string) TokenExtractor {
+	return func(r *http.Request) string {
+		for _, h := range r.Header.Values("Sec-WebSocket-Protocol") {
+			for _, p := range strings.Split(h, ",") {
+				if p = strings.TrimSpace(p); strings.HasPrefix(p, prefix) {
+					return strings.TrimPrefix(p, prefix)
+				}
+			}
+		}
+		return ""
+	}
+}
+
+// WithTokenExtractors sets where the handshake token is looked up; the first
+// non-empty result wins. The default is TokenFromQuery("token").
+func WithTokenExtractors(extractors ...TokenExtractor) WSAuthOption {
+	return func(m *WSAuthMiddleware) {
+		m.extractors = extractors
+	}
+}
+
+func (a *WSAuthMiddleware) extractToken(r *http.Request) string {
+	for _, ex := range a.extractors {
+		if token := ex(r); token != "" {
+			return token
+		}
+	}
+	return ""
+}
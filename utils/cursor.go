@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	apperr "github.com/shadowofcards/go-toolkit/errors"
+)
+
+// cursorPrefix guards against arbitrary base64 being accepted as a cursor.
+const cursorPrefix = "c1:"
+
+type CursorMeta struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	Limit      int64  `json:"limit"`
+}
+
+// CursorPagination reads ?cursor=...&limit=... for keyset pagination. The
+// cursor is opaque to clients and carries the last id of the previous page.
+type CursorPagination struct {
+	DefaultLimit int64
+	MaxLimit     int64
+}
+
+func NewCursorPagination() CursorPagination {
+	return CursorPagination{DefaultLimit: 10, MaxLimit: 100}
+}
+
+// Parse returns the last id encoded in the cursor ("" for the first page) and
+// the clamped limit. A cursor that was not produced by NextMeta is rejected
+// with 400 INVALID_CURSOR.
+func (p CursorPagination) Parse(c fiber.Ctx) (cursor string, limit int64, err error) {
+	limit, _ = strconv.ParseInt(c.Query("limit"), 10, 64)
+	if limit < 1 {
+		limit = p.DefaultLimit
+	}
+	if limit > p.MaxLimit {
+		limit = p.MaxLimit
+	}
+
+	raw := c.Query("cursor")
+	if raw == "" {
+		return "", limit, nil
+	}
+	b, derr := base64.RawURLEncoding.DecodeString(raw)
+	if derr != nil || len(b) <= len(cursorPrefix) || string(b[:len(cursorPrefix)]) != cursorPrefix {
+		return "", 0, apperr.New().
+			WithHTTPStatus(http.StatusBadRequest).
+			WithCode("INVALID_CURSOR").
+			WithMessage("invalid pagination cursor").
+			WithError(derr)
+	}
+	return string(b[len(cursorPrefix):]), limit, nil
+}
+
+// NextMeta encodes lastID as the cursor of the next page; an empty lastID
+// (last page) yields no cursor.
+func (p CursorPagination) NextMeta(lastID string, limit int64) *CursorMeta {
+	m := &CursorMeta{Limit: limit}
+	if lastID != "" {
+		m.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + lastID))
+	}
+	return m
+}
+
+// CursorPaginated responds 200 with data and the meta built by NextMeta.
+func CursorPaginated(c fiber.Ctx, data any, meta *CursorMeta) error {
+	env := Envelope{Data: data}
+	if meta != nil {
+		env.Meta = meta
+	}
+	return c.Status(http.StatusOK).JSON(env)
+}
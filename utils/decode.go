@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"encoding/json"
+	stdErrors "errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	apperr "github.com/shadowofcards/go-toolkit/errors"
+)
+
+// bodyError turns a JSON decode failure into an INVALID_BODY error whose context
+// maps the offending field (or "body") to a readable reason. Unrecognized errors
+// keep the generic message.
+func bodyError(err error) *apperr.AppError {
+	e := apperr.New().
+		WithHTTPStatus(http.StatusBadRequest).
+		WithCode("INVALID_BODY").
+		WithMessage("invalid json body").
+		WithError(err)
+
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case stdErrors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return e.WithContext(field, "expected "+typeErr.Type.String()+", got "+typeErr.Value)
+	case stdErrors.As(err, &syntaxErr):
+		return e.WithContext("body", "malformed json at offset "+strconv.FormatInt(syntaxErr.Offset, 10))
+	case stdErrors.Is(err, io.ErrUnexpectedEOF):
+		return e.WithContext("body", "unexpected end of json")
+	}
+	return e
+}
@@ -0,0 +1,34 @@
+package utils
+
+import "github.com/gofiber/fiber/v3"
+
+// Guards vs Must helpers:
+//
+// RequireTenant/RequireUser are route middlewares that reject the request with
+// the same *AppError returned by GetTenantID/GetUserID (400/401), without
+// panicking. Mount them on routes that need identity; handlers behind them can
+// then call MustGetTenantID/MustGetUserID safely.
+//
+// The Must* helpers panic with the *AppError itself, so when they are used
+// without a guard the app must install fiber's recover middleware, which hands
+// the panicked error to the error handler unchanged.
+
+// RequireTenant rejects requests without a valid tenant ID in Locals.
+func RequireTenant() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if _, err := GetTenantID(c); err != nil {
+			return err
+		}
+		return c.Next()
+	}
+}
+
+// RequireUser rejects requests without a valid user ID in Locals.
+func RequireUser() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if _, err := GetUserID(c); err != nil {
+			return err
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/recover"
+	"github.com/google/uuid"
+
+	apperr "github.com/shadowofcards/go-toolkit/errors"
+)
+
+func guardApp(locals map[string]any, guards ...fiber.Handler) *fiber.App {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c fiber.Ctx, err error) error {
+			if ae, ok := apperr.FromError(err); ok {
+				return c.Status(ae.Status()).SendString(ae.ErrCode())
+			}
+			return c.SendStatus(http.StatusInternalServerError)
+		},
+	})
+	app.Use(func(c fiber.Ctx) error {
+		for k, v := range locals {
+			c.Locals(k, v)
+		}
+		return c.Next()
+	})
+	for _, g := range guards {
+		app.Use(g)
+	}
+	app.Get("/", func(c fiber.Ctx) error {
+		MustGetTenantID(c)
+		MustGetUserID(c)
+		return c.SendStatus(http.StatusNoContent)
+	})
+	return app
+}
+
+func TestRequireTenantAndUser(t *testing.T) {
+	valid := uuid.NewString()
+	tests := []struct {
+		name   string
+		locals map[string]any
+		status int
+		code   string
+	}{
+		{"missing tenant", map[string]any{"userID": valid}, http.StatusBadRequest, "TENANT_ID_MISSING"},
+		{"invalid tenant", map[string]any{"tenantID": "nope", "userID": valid}, http.StatusBadRequest, "TENANT_ID_INVALID"},
+		{"missing user", map[string]any{"tenantID": valid}, http.StatusUnauthorized, "USER_ID_MISSING"},
+		{"invalid user", map[string]any{"tenantID": valid, "userID": ""}, http.StatusUnauthorized, "USER_ID_INVALID"},
+		{"both present", map[string]any{"tenantID": valid, "userID": valid}, http.StatusNoContent, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := guardApp(tt.locals, RequireTenant(), RequireUser())
+			res, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.status)
+			}
+			if tt.code == "" {
+				return
+			}
+			body, _ := io.ReadAll(res.Body)
+			if got := string(body); got != tt.code {
+				t.Fatalf("code = %q, want %q", got, tt.code)
+			}
+		})
+	}
+}
+
+func TestMustGetTenantIDPanicsWithAppError(t *testing.T) {
+	app := guardApp(nil, recover.New())
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", res.StatusCode, http.StatusBadRequest)
+	}
+}
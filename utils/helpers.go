@@ -1,10 +1,15 @@
 package utils
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"reflect"
+	"strconv"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
+	"github.com/shadowofcards/go-toolkit/contexts"
 	apperr "github.com/shadowofcards/go-toolkit/errors"
 )
 
@@ -71,11 +76,7 @@ func GetPathID(c fiber.Ctx) (uuid.UUID, error) {
 
 func GetBody(c fiber.Ctx, v any) error {
 	if err := c.Bind().JSON(v); err != nil {
-		return apperr.New().
-			WithHTTPStatus(http.StatusBadRequest).
-			WithCode("INVALID_BODY").
-			WithMessage("invalid json body").
-			WithError(err)
+		return bodyError(err)
 	}
 	return nil
 }
@@ -110,7 +111,16 @@ func ParseOptionalUUID(s string) (*uuid.UUID, error) {
 }
 
 func GetTenantID(c fiber.Ctx) (uuid.UUID, error) {
-	raw := c.Locals("tenantID")
+	return tenantIDFrom(c.Locals("tenantID"))
+}
+
+// TenantIDFromContext is GetTenantID for code outside Fiber handlers; it reads
+// contexts.KeyTenantID.
+func TenantIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return tenantIDFrom(ctx.Value(contexts.KeyTenantID))
+}
+
+func tenantIDFrom(raw any) (uuid.UUID, error) {
 	if raw == nil {
 		return uuid.Nil, apperr.New().
 			WithHTTPStatus(http.StatusBadRequest).
@@ -135,6 +145,8 @@ func GetTenantID(c fiber.Ctx) (uuid.UUID, error) {
 	return id, nil
 }
 
+// MustGetTenantID panics with the *AppError from GetTenantID. Prefer guarding
+// the route with RequireTenant.
 func MustGetTenantID(c fiber.Ctx) uuid.UUID {
 	id, err := GetTenantID(c)
 	if err != nil {
@@ -144,7 +156,16 @@ func MustGetTenantID(c fiber.Ctx) uuid.UUID {
 }
 
 func GetUserID(c fiber.Ctx) (uuid.UUID, error) {
-	raw := c.Locals("userID")
+	return userIDFrom(c.Locals("userID"))
+}
+
+// UserIDFromContext is GetUserID for code outside Fiber handlers; it reads
+// contexts.KeyUserID.
+func UserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	return userIDFrom(ctx.Value(contexts.KeyUserID))
+}
+
+func userIDFrom(raw any) (uuid.UUID, error) {
 	if raw == nil {
 		return uuid.Nil, apperr.New().
 			WithHTTPStatus(http.StatusUnauthorized).
@@ -169,6 +190,8 @@ func GetUserID(c fiber.Ctx) (uuid.UUID, error) {
 	return id, nil
 }
 
+// MustGetUserID panics with the *AppError from GetUserID. Prefer guarding
+// the route with RequireUser.
 func MustGetUserID(c fiber.Ctx) uuid.UUID {
 	id, err := GetUserID(c)
 	if err != nil {
@@ -203,3 +226,92 @@ func GetUUIDParam(c fiber.Ctx, name string) (uuid.UUID, error) {
 	}
 	return id, nil
 }
+
+var uuidType = reflect.TypeOf(uuid.UUID{})
+
+// BindParams binds path params into the struct pointed to by v using `params`
+// tags (e.g. `params:"tid"`). Supported field types are uuid.UUID, string, ints,
+// uints and bool. Every missing or unparsable param is reported in the context
+// of a single INVALID_PARAM error.
+func BindParams(c fiber.Ctx, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return apperr.New().
+			WithCode("INVALID_BIND_TARGET").
+			WithMessage("BindParams requires a pointer to struct")
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	invalid := map[string]interface{}{}
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		name := f.Tag.Get("params")
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		raw := c.Params(name)
+		if raw == "" {
+			invalid[name] = "missing"
+			continue
+		}
+		if err := setParam(rv.Field(i), raw); err != nil {
+			invalid[name] = err.Error()
+		}
+	}
+
+	if len(invalid) > 0 {
+		e := apperr.New().
+			WithHTTPStatus(http.StatusBadRequest).
+			WithCode("INVALID_PARAM").
+			WithMessage("invalid path params")
+		for k, reason := range invalid {
+			e = e.WithContext(k, reason)
+		}
+		return e
+	}
+	return nil
+}
+
+func setParam(fv reflect.Value, raw string) error {
+	if fv.Type() == uuidType {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(id))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// APIVersion returns the version negotiated by the api version middleware, or
+// "" when the middleware is not installed.
+func APIVersion(c fiber.Ctx) string {
+	v, _ := c.Context().Value(contexts.KeyAPIVersion).(string)
+	return v
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	apperr "github.com/shadowofcards/go-toolkit/errors"
+)
+
+type nestedParams struct {
+	TenantID uuid.UUID `params:"tid"`
+	UserID   uuid.UUID `params:"uid"`
+	Page     int       `params:"page"`
+	Slug     string    `params:"slug"`
+	Active   bool      `params:"active"`
+}
+
+// bindParams runs BindParams against url on a /tenants/:tid/... route.
+func bindParams(t *testing.T, url string) (nestedParams, error) {
+	t.Helper()
+	var (
+		got     nestedParams
+		bindErr error
+	)
+	app := fiber.New()
+	app.Get("/tenants/:tid/users/:uid/pages/:page/:slug/:active", func(c fiber.Ctx) error {
+		bindErr = BindParams(c, &got)
+		return nil
+	})
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, url, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return got, bindErr
+}
+
+func TestBindParamsMixedTypes(t *testing.T) {
+	tid, uid := uuid.New(), uuid.New()
+	got, err := bindParams(t, "/tenants/"+tid.String()+"/users/"+uid.String()+"/pages/3/intro/true")
+	if err != nil {
+		t.Fatalf("BindParams: %v", err)
+	}
+	want := nestedParams{TenantID: tid, UserID: uid, Page: 3, Slug: "intro", Active: true}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestBindParamsReportsEveryInvalidParam(t *testing.T) {
+	_, err := bindParams(t, "/tenants/not-a-uuid/users/"+uuid.NewString()+"/pages/three/intro/yes")
+	ae, ok := apperr.FromError(err)
+	if !ok {
+		t.Fatalf("got %v, want an AppError", err)
+	}
+	if ae.ErrCode() != "INVALID_PARAM" || ae.Status() != http.StatusBadRequest {
+		t.Fatalf("got %s/%d, want INVALID_PARAM/400", ae.ErrCode(), ae.Status())
+	}
+	for _, name := range []string{"tid", "page", "active"} {
+		if _, ok := ae.Context[name]; !ok {
+			t.Errorf("context is missing %q: %v", name, ae.Context)
+		}
+	}
+	for _, name := range []string{"uid", "slug"} {
+		if _, ok := ae.Context[name]; ok {
+			t.Errorf("valid param %q reported as invalid", name)
+		}
+	}
+}
+
+func TestBindParamsRequiresStructPointer(t *testing.T) {
+	app := fiber.New()
+	var bindErr error
+	app.Get("/:id", func(c fiber.Ctx) error {
+		bindErr = BindParams(c, nestedParams{})
+		return nil
+	})
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/1", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if !apperr.HasCode(bindErr, "INVALID_BIND_TARGET") {
+		t.Fatalf("got %v, want INVALID_BIND_TARGET", bindErr)
+	}
+}
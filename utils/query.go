@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	apperr "github.com/shadowofcards/go-toolkit/errors"
+)
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort reads ?sort=-createdAt,name ("-" for descending) and rejects
+// fields outside allowed with 400 INVALID_SORT.
+func ParseSort(c fiber.Ctx, allowed []string) ([]SortField, error) {
+	raw := c.Query("sort")
+	if raw == "" {
+		return nil, nil
+	}
+	var out []SortField
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		f := SortField{Field: strings.TrimLeft(part, "+-"), Desc: strings.HasPrefix(part, "-")}
+		if !slices.Contains(allowed, f.Field) {
+			return nil, apperr.New().
+				WithHTTPStatus(http.StatusBadRequest).
+				WithCode("INVALID_SORT").
+				WithMessage("unsupported sort field").
+				WithContext("field", f.Field).
+				WithContext("allowed", allowed)
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// ParseFilters reads ?filter[status]=active parameters and rejects fields
+// outside allowed with 400 INVALID_FILTER.
+func ParseFilters(c fiber.Ctx, allowed []string) (map[string]string, error) {
+	out := map[string]string{}
+	for k, v := range c.Queries() {
+		if !strings.HasPrefix(k, "filter[") || !strings.HasSuffix(k, "]") {
+			continue
+		}
+		field := k[len("filter[") : len(k)-1]
+		if !slices.Contains(allowed, field) {
+			return nil, apperr.New().
+				WithHTTPStatus(http.StatusBadRequest).
+				WithCode("INVALID_FILTER").
+				WithMessage("unsupported filter field").
+				WithContext("field", field).
+				WithContext("allowed", allowed)
+		}
+		out[field] = v
+	}
+	return out, nil
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Envelope is the body of every success response: {"data": ..., "meta": ...}.
+type Envelope struct {
+	Data any `json:"data"`
+	Meta any `json:"meta,omitempty"`
+}
+
+// OK responds 200 with data in the envelope.
+func OK(c fiber.Ctx, data any) error {
+	return c.Status(http.StatusOK).JSON(Envelope{Data: data})
+}
+
+// Created responds 201 with data in the envelope.
+func Created(c fiber.Ctx, data any) error {
+	return c.Status(http.StatusCreated).JSON(Envelope{Data: data})
+}
+
+// Paginated responds 200 with data and the meta built by Pagination.Meta.
+func Paginated(c fiber.Ctx, data any, meta *PaginationMeta) error {
+	env := Envelope{Data: data}
+	if meta != nil {
+		env.Meta = meta
+	}
+	return c.Status(http.StatusOK).JSON(env)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"net/url"
 	"time"
 
 	httpws "github.com/gorilla/websocket"
@@ -12,6 +11,7 @@ import (
 
 	"github.com/shadowofcards/go-toolkit/contexts"
 	apperr "github.com/shadowofcards/go-toolkit/errors"
+	"github.com/shadowofcards/go-toolkit/lifecycle"
 	"github.com/shadowofcards/go-toolkit/logging"
 	"github.com/shadowofcards/go-toolkit/metrics"
 )
@@ -23,11 +23,7 @@ import (
 type Middleware func(next http.HandlerFunc) http.HandlerFunc
 type HandlerFunc func(ctx context.Context, conn *SafeConn)
 
-type errorPayload struct {
-	Code    string      `json:"code"`
-	Message string      `json:"message"`
-	Context interface{} `json:"context,omitempty"`
-}
+var errInternal = apperr.Define("INTERNAL_ERROR", http.StatusInternalServerError, "internal server error")
 
 type HeartbeatPublisher interface {
 	PublishHeartbeat(ctx context.Context, id string) error
@@ -44,6 +40,12 @@ type Handler struct {
 	pingPeriod         time.Duration
 	allowedOrigins     []string
 	metrics            metrics.Recorder
+	reauth             Reauthenticator
+	readiness          *lifecycle.Readiness
+	closeGrace         time.Duration
+	msgLimit           *msgLimit
+	originValidator    func(origin string) bool
+	emptyOrigin        *bool
 }
 
 const (
@@ -63,39 +65,25 @@ func NewHandler(m Manager, opts ...Option) *Handler {
 			EnableCompression: false,
 		},
 		manager:            m,
-		handle:             defaultEcho,
 		logger:             logger,
 		pongWait:           defaultPongWait,
 		pingPeriod:         defaultPingPeriod,
 		heartbeatPublisher: nil,
 		allowedOrigins:     nil,
-		metrics:            nil,
 	}
 	for _, o := range opts {
 		o(h)
 	}
-	if h.metrics != nil {
-		h.handle = h.echoWithMetrics
-	}
-	h.upgrader.CheckOrigin = func(r *http.Request) bool {
-		origin := r.Header.Get("Origin")
-		if len(h.allowedOrigins) > 0 {
-			for _, a := range h.allowedOrigins {
-				if a == origin {
-					return true
-				}
-			}
-			return false
-		}
-		if origin == "" {
-			return true
-		}
-		u, err := url.Parse(origin)
-		if err != nil {
-			return false
+	if h.handle == nil {
+		h.handle = defaultEcho
+		if h.metrics != nil {
+			h.handle = h.echoWithMetrics
 		}
-		return u.Host == r.Host
 	}
+	if h.metrics == nil {
+		h.metrics = metrics.NoOp()
+	}
+	h.upgrader.CheckOrigin = h.checkOrigin
 	return h
 }
 
@@ -103,7 +91,13 @@ func (h *Handler) Use(mw Middleware) {
 	h.middlewares = append(h.middlewares, mw)
 }
 
+var ErrDraining = apperr.Define("DRAINING", http.StatusServiceUnavailable, "server is shutting down")
+
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.readiness != nil && !h.readiness.Ready() {
+		h.handleError(r.Context(), w, ErrDraining)
+		return
+	}
 	final := func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		pid, _ := ctx.Value(contexts.KeyPlayerID).(string)
@@ -123,7 +117,18 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		defer rawConn.Close()
 
-		if err := h.manager.Register(ctx, pid, rawConn); err != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		ctx = context.WithValue(ctx, ctxKeyConnCancel{}, cancel)
+
+		conn := &SafeConn{Conn: rawConn, ctx: ctx, reauth: h.reauth, closeGrace: h.closeGrace}
+		conn.limiter = h.msgLimit.newLimiter(func() {
+			if h.metrics != nil {
+				h.metrics.IncWithTags(ctx, "ws_rate_limited_total", 1, map[string]string{"player_id": pid})
+			}
+		})
+		connID, err := h.manager.RegisterSafeConn(ctx, pid, conn)
+		if err != nil {
 			if h.metrics != nil {
 				h.metrics.Inc(ctx, "errors_total", 1)
 			}
@@ -135,7 +140,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 
 		defer func() {
-			h.manager.Unregister(ctx, pid)
+			h.manager.UnregisterConn(ctx, connID, conn)
 			if h.metrics != nil {
 				h.metrics.Gauge(ctx, "connections_active", float64(h.manager.ActiveCount(ctx)))
 				dur := time.Since(start).Milliseconds()
@@ -143,14 +148,13 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			}
 		}()
 
-		conn := &SafeConn{Conn: rawConn}
 		conn.SetReadLimit(1 << 20)
 		conn.SetReadDeadline(time.Now().Add(h.pongWait))
 
 		var pingTime time.Time
 		conn.SetPongHandler(func(string) error {
 			conn.SetReadDeadline(time.Now().Add(h.pongWait))
-			h.manager.Refresh(ctx, pid)
+			h.manager.Refresh(ctx, connID)
 			if h.heartbeatPublisher != nil {
 				h.heartbeatPublisher.PublishHeartbeat(ctx, pid)
 			}
@@ -170,6 +174,10 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				case <-ticker.C:
 					pingTime = time.Now()
 					_ = conn.WriteControl(httpws.PingMessage, nil, time.Now().Add(5*time.Second))
+				case <-ctx.Done():
+					// Disconnect or shutdown: unblock the read loop.
+					_ = conn.SetReadDeadline(time.Now())
+					return
 				case <-done:
 					return
 				}
@@ -177,8 +185,9 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}()
 
 		h.logger.InfoCtx(ctx, "ws connected", zap.String("player", pid))
-		h.handle(ctx, conn)
+		h.runHandler(ctx, conn, pid)
 		close(done)
+		_ = conn.CloseWithCode(httpws.CloseNormalClosure, "")
 	}
 
 	handler := final
@@ -188,6 +197,25 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	handler(w, r)
 }
 
+// runHandler isolates panics raised by the user HandlerFunc so they are logged
+// and counted instead of unwinding ServeHTTP; the connection cleanup deferred by
+// the caller then runs normally.
+func (h *Handler) runHandler(ctx context.Context, conn *SafeConn, pid string) {
+	defer func() {
+		if r := recover(); r != nil {
+			if h.metrics != nil {
+				h.metrics.IncWithTags(ctx, "errors_total", 1, map[string]string{"stage": "handler_panic", "player_id": pid})
+			}
+			h.logger.ErrorCtx(ctx, "ws handler panic",
+				zap.String("player", pid),
+				zap.Any("panic", r),
+				zap.Stack("stack"),
+			)
+		}
+	}()
+	h.handle(ctx, conn)
+}
+
 func (h *Handler) echoWithMetrics(ctx context.Context, conn *SafeConn) {
 	for {
 		mt, msg, err := conn.ReadMessage()
@@ -226,19 +254,19 @@ func defaultEcho(ctx context.Context, conn *SafeConn) {
 }
 
 func (h *Handler) handleError(ctx context.Context, w http.ResponseWriter, err error) {
-	var payload errorPayload
-	status := http.StatusInternalServerError
-
-	if ae, ok := apperr.FromError(err); ok {
-		payload = errorPayload{Code: ae.ErrCode(), Message: ae.Message, Context: ae.Context}
-		status = ae.Status()
-		h.logger.WarnCtx(ctx, "ws app error", zap.String("code", payload.Code), zap.Error(err))
+	ae, ok := apperr.FromError(err)
+	if ok {
+		h.logger.WarnCtx(ctx, "ws app error",
+			zap.String("code", ae.ErrCode()),
+			zap.Any("internal", ae.Internal),
+			zap.Error(err),
+		)
 	} else {
-		payload = errorPayload{Code: "INTERNAL_ERROR", Message: "internal server error"}
+		ae = errInternal
 		h.logger.ErrorCtx(ctx, "ws internal error", zap.Error(err))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": payload})
+	w.WriteHeader(ae.Status())
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": ae})
 }
@@ -0,0 +1,106 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	httpws "github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/shadowofcards/go-toolkit/contexts"
+	"github.com/shadowofcards/go-toolkit/logging"
+)
+
+// serve starts h behind a middleware that sets the player id from ?pid=.
+func serve(t *testing.T, h *Handler) string {
+	t.Helper()
+	h.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), contexts.KeyPlayerID, r.URL.Query().Get("pid"))
+			next(w, r.WithContext(ctx))
+		}
+	})
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func quietLogger() Option { return WithLogger(&logging.Logger{Logger: zap.NewNop()}) }
+
+func dial(t *testing.T, url string, protocols ...string) (*httpws.Conn, *http.Response) {
+	t.Helper()
+	d := httpws.Dialer{Subprotocols: protocols, HandshakeTimeout: 2 * time.Second}
+	c, res, err := d.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", url, err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, res
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPanickingHandlerIsUnregistered(t *testing.T) {
+	m := NewManager()
+	h := NewHandler(m, quietLogger(), WithHandlerFunc(func(ctx context.Context, conn *SafeConn) {
+		panic("boom")
+	}))
+	url := serve(t, h) + "?pid=p1"
+
+	c, _ := dial(t, url)
+	_ = c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := c.ReadMessage(); !httpws.IsCloseError(err, httpws.CloseNormalClosure) {
+		t.Fatalf("read after panic: got %v, want a normal close", err)
+	}
+	waitFor(t, "the connection to be unregistered", func() bool {
+		return m.ActiveCount(context.Background()) == 0
+	})
+
+	// The player id is free again, so the same player can reconnect.
+	dial(t, url)
+}
+
+func TestSubprotocolNegotiation(t *testing.T) {
+	negotiated := make(chan string, 1)
+	h := NewHandler(NewManager(), quietLogger(),
+		WithSubprotocols("json.v2"),
+		WithHandlerFunc(func(ctx context.Context, conn *SafeConn) {
+			negotiated <- conn.Subprotocol()
+		}),
+	)
+	url := serve(t, h) + "?pid=p1"
+
+	_, res := dial(t, url, "json.v1", "json.v2")
+	if got := res.Header.Get("Sec-WebSocket-Protocol"); got != "json.v2" {
+		t.Fatalf("Sec-WebSocket-Protocol = %q, want json.v2", got)
+	}
+	select {
+	case got := <-negotiated:
+		if got != "json.v2" {
+			t.Fatalf("conn.Subprotocol() = %q, want json.v2", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler did not run")
+	}
+}
+
+func TestNoSubprotocolWithoutOption(t *testing.T) {
+	h := NewHandler(NewManager(), quietLogger())
+	_, res := dial(t, serve(t, h)+"?pid=p1", "json.v1")
+	if got := res.Header.Get("Sec-WebSocket-Protocol"); got != "" {
+		t.Fatalf("Sec-WebSocket-Protocol = %q, want none", got)
+	}
+}
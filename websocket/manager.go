@@ -4,12 +4,24 @@ import (
 	"context"
 	"net/http"
 	"sync"
+	"time"
 
 	httpws "github.com/gorilla/websocket"
+	"github.com/rs/xid"
 	apperr "github.com/shadowofcards/go-toolkit/errors"
 	"github.com/shadowofcards/go-toolkit/metrics"
 )
 
+var (
+	ErrSendFailed = apperr.Define("SEND_FAILED", http.StatusInternalServerError, "failed to write to connection")
+
+	ErrAlreadyConnected = apperr.Define("ALREADY_CONNECTED", http.StatusConflict, "connection exists")
+
+	ErrNotConnected = apperr.Define("NOT_CONNECTED", http.StatusNotFound, "player not online")
+
+	ErrSendAborted = apperr.Define("SEND_ABORTED", http.StatusRequestTimeout, "send aborted: context done")
+)
+
 type ManagerOption func(*manager)
 
 func WithManagerMetrics(rc metrics.Recorder) ManagerOption {
@@ -18,24 +30,108 @@ func WithManagerMetrics(rc metrics.Recorder) ManagerOption {
 	}
 }
 
+// WithDeadConnectionReaping makes a failed write in SendTo unregister that
+// connection asynchronously, so broadcasts keep the connection set accurate
+// instead of waiting for the read loop to notice the dead socket.
+func WithDeadConnectionReaping() ManagerOption {
+	return func(m *manager) {
+		m.reapDead = true
+	}
+}
+
+// WithWriteTimeout bounds every manager write (SendTo, SendToRoom, Broadcast)
+// when the caller's context carries no deadline of its own.
+func WithWriteTimeout(d time.Duration) ManagerOption {
+	return func(m *manager) {
+		m.writeTimeout = d
+	}
+}
+
+// WithAsyncWrites gives every connection a queue of bufferSize messages drained
+// by its own writer goroutine. SendTo then only enqueues and fails with
+// SLOW_CONSUMER when the queue is full, so a slow client cannot stall
+// broadcasts. Write errors surface through metrics and reaping instead.
+func WithAsyncWrites(bufferSize int) ManagerOption {
+	return func(m *manager) {
+		m.asyncBuf = bufferSize
+	}
+}
+
+// WithMultiConn lets one player id hold several connections (phone, desktop,
+// ...). Each gets a connection id "<id>/<suffix>" returned by RegisterConn;
+// SendTo(id) fans out to all of them, Unregister(connID) removes one and
+// Unregister(id) removes all. Rooms keep tracking player ids.
+func WithMultiConn() ManagerOption {
+	return func(m *manager) {
+		m.multi = true
+	}
+}
+
 type Manager interface {
 	Register(ctx context.Context, id string, raw *httpws.Conn) error
+	RegisterConn(ctx context.Context, id string, raw *httpws.Conn) (connID string, err error)
+	RegisterSafeConn(ctx context.Context, id string, c *SafeConn) (connID string, err error)
 	Unregister(ctx context.Context, id string)
+	UnregisterConn(ctx context.Context, connID string, c *SafeConn)
 	JoinRoom(id, room string)
 	LeaveRoom(id, room string)
 	SendTo(id string, mt int, msg []byte) error
+	SendToCtx(ctx context.Context, id string, mt int, msg []byte) error
 	SendToRoom(room string, mt int, msg []byte)
+	SendToRoomExcept(room, exceptID string, mt int, msg []byte)
 	Broadcast(mt int, msg []byte)
+	SendToRoomE(room string, mt int, msg []byte) SendErrors
+	SendToRoomExceptE(room, exceptID string, mt int, msg []byte) SendErrors
+	BroadcastE(mt int, msg []byte) SendErrors
+	SendJSON(id string, v any) error
+	SendJSONToRoom(room string, v any) error
+	BroadcastJSON(v any) error
+	RoomCount(room string) int
+	Rooms() map[string]int
 	Refresh(ctx context.Context, id string)
+	ReapStale(ctx context.Context, maxIdle time.Duration) int
+	Disconnect(id string, code int, reason string)
 	ActiveCount(ctx context.Context) int
 }
 
+// SendErrors maps the connection ids whose write failed to the error; it is nil
+// when every write succeeded.
+type SendErrors map[string]error
+
+// Err folds the failures into a single errors.Multi, or nil.
+func (e SendErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	var multi apperr.Multi
+	for id, err := range e {
+		if ae, ok := apperr.FromError(err); ok {
+			multi.Append(ae.WithContext("player_id", id))
+			continue
+		}
+		multi.Append(ErrSendFailed.
+			WithError(err).
+			WithContext("player_id", id))
+	}
+	return multi.ErrorOrNil()
+}
+
 type manager struct {
 	conns   map[string]*SafeConn
 	rooms   map[string]map[string]struct{}
 	ctxs    map[string]context.Context
 	mu      sync.RWMutex
 	metrics metrics.Recorder
+
+	reapDead     bool
+	writeTimeout time.Duration
+	asyncBuf     int
+
+	cancels map[string]context.CancelFunc
+
+	multi   bool
+	players map[string]map[string]struct{} // player id -> connection ids
+	owner   map[string]string              // connection id -> player id
 }
 
 func NewManager(opts ...ManagerOption) Manager {
@@ -43,56 +139,185 @@ func NewManager(opts ...ManagerOption) Manager {
 		conns:   make(map[string]*SafeConn),
 		rooms:   make(map[string]map[string]struct{}),
 		ctxs:    make(map[string]context.Context),
-		metrics: nil,
+		players: make(map[string]map[string]struct{}),
+		owner:   make(map[string]string),
+		cancels: make(map[string]context.CancelFunc),
+		metrics: metrics.NoOp(),
 	}
 	for _, o := range opts {
 		o(m)
 	}
+	if m.metrics == nil {
+		m.metrics = metrics.NoOp()
+	}
 	return m
 }
 
 func (m *manager) Register(ctx context.Context, id string, raw *httpws.Conn) error {
+	_, err := m.RegisterConn(ctx, id, raw)
+	return err
+}
+
+// RegisterConn registers raw for player id and returns the id to pass to
+// Unregister: id itself, or a per-connection id with WithMultiConn.
+func (m *manager) RegisterConn(ctx context.Context, id string, raw *httpws.Conn) (string, error) {
+	return m.RegisterSafeConn(ctx, id, &SafeConn{Conn: raw})
+}
+
+// RegisterSafeConn is RegisterConn for a connection the caller already wraps,
+// so the manager and the caller share one write mutex, one set of attributes
+// and one (reauthenticated) context for the socket.
+func (m *manager) RegisterSafeConn(ctx context.Context, id string, c *SafeConn) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, ok := m.conns[id]; ok {
+	connID := id
+	if m.multi {
+		connID = id + "/" + xid.New().String()
+	} else if _, ok := m.conns[id]; ok {
 		if m.metrics != nil {
 			m.metrics.IncWithTags(ctx, "errors_total", 1, map[string]string{"player_id": id, "stage": "register"})
 		}
-		return apperr.New().
-			WithHTTPStatus(http.StatusConflict).
-			WithCode("ALREADY_CONNECTED").
-			WithMessage("connection exists")
+		return "", ErrAlreadyConnected
 	}
 
-	m.conns[id] = &SafeConn{Conn: raw}
-	m.ctxs[id] = ctx
+	c.lastSeen.Store(time.Now().UnixNano())
+	if m.asyncBuf > 0 {
+		c.startWriter(m.asyncBuf,
+			func() time.Time { return m.writeDeadline(context.Background()) },
+			func(error) { m.writeFailed(ctx, connID, c) })
+	}
+	m.conns[connID] = c
+	m.ctxs[connID] = ctx
+	if cancel, ok := ctx.Value(ctxKeyConnCancel{}).(context.CancelFunc); ok {
+		m.cancels[connID] = cancel
+	}
+	if m.multi {
+		if m.players[id] == nil {
+			m.players[id] = make(map[string]struct{})
+		}
+		m.players[id][connID] = struct{}{}
+		m.owner[connID] = id
+	}
 
 	if m.metrics != nil {
 		m.metrics.GaugeWithTags(ctx, "connections_active", float64(len(m.conns)), map[string]string{"player_id": id})
 	}
+	return connID, nil
+}
+
+// targets resolves id to connection ids: a connection id maps to itself, a
+// player id to all of its connections. Callers hold m.mu.
+func (m *manager) targets(id string) []string {
+	if _, ok := m.conns[id]; ok {
+		return []string{id}
+	}
+	set := m.players[id]
+	ids := make([]string, 0, len(set))
+	for connID := range set {
+		ids = append(ids, connID)
+	}
+	return ids
+}
+
+// Disconnect kicks id (every connection of the player with WithMultiConn): a
+// close frame with code and reason is sent, the connection context is
+// cancelled and the handler's read loop returns and unregisters it.
+// Connections registered outside Handler are unregistered directly.
+func (m *manager) Disconnect(id string, code int, reason string) {
+	type kick struct {
+		connID string
+		c      *SafeConn
+		ctx    context.Context
+		cancel context.CancelFunc
+	}
+	m.mu.RLock()
+	var kicks []kick
+	for _, connID := range m.targets(id) {
+		kicks = append(kicks, kick{connID, m.conns[connID], m.ctxs[connID], m.cancels[connID]})
+	}
+	m.mu.RUnlock()
+
+	for _, k := range kicks {
+		_ = k.c.CloseWithCode(code, reason)
+		if m.metrics != nil {
+			m.metrics.IncWithTags(k.ctx, "connections_kicked_total", 1, map[string]string{"player_id": id})
+		}
+		if k.cancel != nil {
+			k.cancel()
+			continue
+		}
+		m.mu.Lock()
+		if cur, ok := m.conns[k.connID]; ok && cur == k.c {
+			m.unregisterLocked(k.ctx, k.connID)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// ctxOf returns the context of any connection of id, or nil. Callers hold m.mu.
+func (m *manager) ctxOf(id string) context.Context {
+	if ids := m.targets(id); len(ids) > 0 {
+		return m.ctxs[ids[0]]
+	}
 	return nil
 }
 
 func (m *manager) Unregister(ctx context.Context, id string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.unregisterLocked(ctx, id)
+}
 
-	if c, ok := m.conns[id]; ok {
-		_ = c.Close()
-		delete(m.conns, id)
+// UnregisterConn removes connID only while it is still registered to c. A
+// handler uses it on exit so that, once its connection was reaped or kicked, it
+// cannot remove a newer connection registered under the same id.
+func (m *manager) UnregisterConn(ctx context.Context, connID string, c *SafeConn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.conns[connID]; ok && cur == c {
+		m.unregisterLocked(ctx, connID)
+	}
+}
+
+func (m *manager) unregisterLocked(ctx context.Context, id string) {
+	player := id
+	for _, connID := range m.targets(id) {
+		if c, ok := m.conns[connID]; ok {
+			if c.queue != nil {
+				c.queue.stop()
+			}
+			_ = c.Close()
+			delete(m.conns, connID)
+		}
+		delete(m.ctxs, connID)
+		delete(m.cancels, connID)
+		if p, ok := m.owner[connID]; ok {
+			player = p
+			delete(m.owner, connID)
+			delete(m.players[p], connID)
+		}
 	}
-	delete(m.ctxs, id)
 
 	if m.metrics != nil {
-		m.metrics.GaugeWithTags(ctx, "connections_active", float64(len(m.conns)), map[string]string{"player_id": id})
+		m.metrics.GaugeWithTags(ctx, "connections_active", float64(len(m.conns)), map[string]string{"player_id": player})
 	}
 
+	if len(m.players[player]) > 0 {
+		return
+	}
+	delete(m.players, player)
 	for room, set := range m.rooms {
-		delete(set, id)
+		if _, ok := set[player]; !ok {
+			continue
+		}
+		delete(set, player)
 		if len(set) == 0 {
 			delete(m.rooms, room)
 		}
+		if m.metrics != nil {
+			m.recordRoomSize(ctx, room)
+		}
 	}
 }
 
@@ -105,8 +330,9 @@ func (m *manager) JoinRoom(id, room string) {
 	}
 	m.rooms[room][id] = struct{}{}
 
-	if ctx, ok := m.ctxs[id]; ok && m.metrics != nil {
+	if ctx := m.ctxOf(id); ctx != nil && m.metrics != nil {
 		m.metrics.IncWithTags(ctx, "room_joins_total", 1, map[string]string{"room": room, "player_id": id})
+		m.recordRoomSize(ctx, room)
 	}
 }
 
@@ -121,87 +347,249 @@ func (m *manager) LeaveRoom(id, room string) {
 		}
 	}
 
-	if ctx, ok := m.ctxs[id]; ok && m.metrics != nil {
+	if ctx := m.ctxOf(id); ctx != nil && m.metrics != nil {
 		m.metrics.IncWithTags(ctx, "room_leaves_total", 1, map[string]string{"room": room, "player_id": id})
+		m.recordRoomSize(ctx, room)
 	}
 }
 
+// recordRoomSize emits the room_size gauge. Callers hold m.mu.
+func (m *manager) recordRoomSize(ctx context.Context, room string) {
+	m.metrics.GaugeWithTags(ctx, "room_size", float64(len(m.rooms[room])), map[string]string{"room": room})
+}
+
+// RoomCount returns the number of members of room.
+func (m *manager) RoomCount(room string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.rooms[room])
+}
+
+// Rooms returns a snapshot of every room and its member count.
+func (m *manager) Rooms() map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make(map[string]int, len(m.rooms))
+	for room, set := range m.rooms {
+		out[room] = len(set)
+	}
+	return out
+}
+
 func (m *manager) SendTo(id string, mt int, msg []byte) error {
+	return m.SendToCtx(context.Background(), id, mt, msg)
+}
+
+// SendToCtx writes to a single connection (every connection of the player with
+// WithMultiConn), giving up once sendCtx is done. The write deadline is
+// sendCtx's deadline, or the configured write timeout.
+func (m *manager) SendToCtx(sendCtx context.Context, id string, mt int, msg []byte) error {
 	m.mu.RLock()
-	c, ok := m.conns[id]
-	ctx := m.ctxs[id]
+	ids := m.targets(id)
+	conns := make([]*SafeConn, len(ids))
+	ctxs := make([]context.Context, len(ids))
+	for i, connID := range ids {
+		conns[i], ctxs[i] = m.conns[connID], m.ctxs[connID]
+		if ctxs[i] == nil {
+			ctxs[i] = sendCtx
+		}
+	}
 	m.mu.RUnlock()
 
-	if !ok {
+	if len(ids) == 0 {
 		if m.metrics != nil {
-			m.metrics.IncWithTags(ctx, "errors_total", 1, map[string]string{"stage": "send_to", "player_id": id})
+			m.metrics.IncWithTags(sendCtx, "errors_total", 1, map[string]string{"stage": "send_to", "player_id": id})
 		}
-		return apperr.New().
-			WithHTTPStatus(http.StatusNotFound).
-			WithCode("NOT_CONNECTED").
-			WithMessage("player not online")
+		return ErrNotConnected
 	}
 
-	err := c.WriteMessage(mt, msg)
-	if err != nil && m.metrics != nil {
-		m.metrics.IncWithTags(ctx, "errors_total", 1, map[string]string{"stage": "write", "player_id": id})
+	if err := sendCtx.Err(); err != nil {
+		return ErrSendAborted.WithError(err)
 	}
-	return err
-}
 
-func (m *manager) SendToRoom(room string, mt int, msg []byte) {
-	m.mu.RLock()
-	set, ok := m.rooms[room]
-	m.mu.RUnlock()
-	if !ok {
-		return
+	if len(ids) == 1 {
+		return m.sendOne(sendCtx, ctxs[0], ids[0], conns[0], mt, msg)
 	}
+	var errs SendErrors
+	for i, connID := range ids {
+		if err := m.sendOne(sendCtx, ctxs[i], connID, conns[i], mt, msg); err != nil {
+			if errs == nil {
+				errs = SendErrors{}
+			}
+			errs[connID] = err
+		}
+	}
+	return errs.Err()
+}
 
-	m.mu.RLock()
-	ids := make([]string, 0, len(set))
-	for id := range set {
-		ids = append(ids, id)
+func (m *manager) sendOne(sendCtx, ctx context.Context, id string, c *SafeConn, mt int, msg []byte) error {
+	if c.queue != nil {
+		err := c.queue.enqueue(mt, msg)
+		if err != nil && m.metrics != nil {
+			m.metrics.IncWithTags(ctx, "errors_total", 1, map[string]string{"stage": "enqueue", "player_id": id})
+		}
+		return err
 	}
-	m.mu.RUnlock()
 
-	var ctx context.Context
-	if len(ids) > 0 {
-		ctx = m.ctxs[ids[0]]
+	err := c.WriteMessageDeadline(mt, msg, m.writeDeadline(sendCtx))
+	if err != nil {
+		m.writeFailed(ctx, id, c)
 	}
+	return err
+}
 
+func (m *manager) writeFailed(ctx context.Context, id string, c *SafeConn) {
 	if m.metrics != nil {
-		m.metrics.IncWithTags(ctx, "broadcasts_total", 1, map[string]string{"room": room, "type": "room"})
+		m.metrics.IncWithTags(ctx, "errors_total", 1, map[string]string{"stage": "write", "player_id": id})
+	}
+	if m.reapDead {
+		m.reap(ctx, id, c)
 	}
+}
 
-	for _, id := range ids {
-		_ = m.SendTo(id, mt, msg)
+func (m *manager) writeDeadline(ctx context.Context) time.Time {
+	if dl, ok := ctx.Deadline(); ok {
+		return dl
 	}
+	if m.writeTimeout > 0 {
+		return time.Now().Add(m.writeTimeout)
+	}
+	return time.Time{}
+}
+
+// reap unregisters a connection whose write failed. Only the first failure per
+// connection schedules the removal, and a newer connection registered under the
+// same id in the meantime is left untouched.
+func (m *manager) reap(ctx context.Context, id string, c *SafeConn) {
+	if !c.dead.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if cur, ok := m.conns[id]; !ok || cur != c {
+			return
+		}
+		m.unregisterLocked(ctx, id)
+		if m.metrics != nil {
+			m.metrics.IncWithTags(ctx, "ws_dead_connection_total", 1, map[string]string{"player_id": id})
+		}
+	}()
+}
+
+// SendToRoom, SendToRoomExcept and Broadcast ignore per-connection failures;
+// use the E variants to learn which connections failed.
+func (m *manager) SendToRoom(room string, mt int, msg []byte) {
+	m.sendToRoom(room, "", "room", mt, msg)
+}
+
+// SendToRoomExcept sends to every member of room but exceptID, typically the
+// sender of the message being relayed.
+func (m *manager) SendToRoomExcept(room, exceptID string, mt int, msg []byte) {
+	m.sendToRoom(room, exceptID, "room_except", mt, msg)
 }
 
 func (m *manager) Broadcast(mt int, msg []byte) {
+	m.BroadcastE(mt, msg)
+}
+
+func (m *manager) SendToRoomE(room string, mt int, msg []byte) SendErrors {
+	return m.sendToRoom(room, "", "room", mt, msg)
+}
+
+func (m *manager) SendToRoomExceptE(room, exceptID string, mt int, msg []byte) SendErrors {
+	return m.sendToRoom(room, exceptID, "room_except", mt, msg)
+}
+
+func (m *manager) BroadcastE(mt int, msg []byte) SendErrors {
 	m.mu.RLock()
 	ids := make([]string, 0, len(m.conns))
 	for id := range m.conns {
 		ids = append(ids, id)
 	}
+	ctx := m.anyCtx(ids)
 	m.mu.RUnlock()
 
-	var ctx context.Context
-	if len(ids) > 0 {
-		ctx = m.ctxs[ids[0]]
+	if m.metrics != nil {
+		m.metrics.IncWithTags(ctx, "broadcasts_total", 1, map[string]string{"type": "global"})
+	}
+	return m.sendAll(ids, mt, msg)
+}
+
+func (m *manager) sendToRoom(room, exceptID, kind string, mt int, msg []byte) SendErrors {
+	m.mu.RLock()
+	set, ok := m.rooms[room]
+	if !ok {
+		m.mu.RUnlock()
+		return nil
+	}
+	ids := make([]string, 0, len(set))
+	for id := range set {
+		if id != exceptID {
+			ids = append(ids, id)
+		}
 	}
+	ctx := m.anyCtx(ids)
+	m.mu.RUnlock()
 
 	if m.metrics != nil {
-		m.metrics.IncWithTags(ctx, "broadcasts_total", 1, map[string]string{"type": "global"})
+		m.metrics.IncWithTags(ctx, "broadcasts_total", 1, map[string]string{"room": room, "type": kind})
 	}
+	return m.sendAll(ids, mt, msg)
+}
 
+func (m *manager) sendAll(ids []string, mt int, msg []byte) SendErrors {
+	var errs SendErrors
 	for _, id := range ids {
-		_ = m.SendTo(id, mt, msg)
+		if err := m.SendTo(id, mt, msg); err != nil {
+			if errs == nil {
+				errs = SendErrors{}
+			}
+			errs[id] = err
+		}
 	}
+	return errs
 }
 
+// anyCtx returns the context of the first registered id, for tagging
+// broadcast metrics. Callers hold m.mu.
+func (m *manager) anyCtx(ids []string) context.Context {
+	if len(ids) > 0 {
+		if ctx, ok := m.ctxs[ids[0]]; ok && ctx != nil {
+			return ctx
+		}
+	}
+	return context.Background()
+}
+
+// Refresh marks id as alive now; the handler calls it on every pong.
 func (m *manager) Refresh(ctx context.Context, id string) {
-	// no-op or update last-seen timestamp if needed
+	now := time.Now().UnixNano()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, connID := range m.targets(id) {
+		m.conns[connID].lastSeen.Store(now)
+	}
+}
+
+// ReapStale closes and unregisters connections not refreshed for maxIdle and
+// returns how many were removed. Run it periodically from a sweeper.
+func (m *manager) ReapStale(ctx context.Context, maxIdle time.Duration) int {
+	cutoff := time.Now().Add(-maxIdle).UnixNano()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reaped := 0
+	for id, c := range m.conns {
+		if c.lastSeen.Load() >= cutoff {
+			continue
+		}
+		m.unregisterLocked(ctx, id)
+		reaped++
+		if m.metrics != nil {
+			m.metrics.IncWithTags(ctx, "connections_reaped_total", 1, map[string]string{"player_id": id})
+		}
+	}
+	return reaped
 }
 
 func (m *manager) ActiveCount(ctx context.Context) int {
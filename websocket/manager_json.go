@@ -0,0 +1,47 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+
+	httpws "github.com/gorilla/websocket"
+	apperr "github.com/shadowofcards/go-toolkit/errors"
+)
+
+var ErrEncode = apperr.Define("ENCODE_ERROR", http.StatusInternalServerError, "failed to encode websocket message")
+
+func encodeJSON(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, ErrEncode.WithError(err)
+	}
+	return b, nil
+}
+
+// SendJSON marshals v and sends it to id as a text message.
+func (m *manager) SendJSON(id string, v any) error {
+	b, err := encodeJSON(v)
+	if err != nil {
+		return err
+	}
+	return m.SendTo(id, httpws.TextMessage, b)
+}
+
+// SendJSONToRoom marshals v once and sends it to every member of room. Failed
+// writes are folded into the returned error (see SendErrors.Err).
+func (m *manager) SendJSONToRoom(room string, v any) error {
+	b, err := encodeJSON(v)
+	if err != nil {
+		return err
+	}
+	return m.SendToRoomE(room, httpws.TextMessage, b).Err()
+}
+
+// BroadcastJSON marshals v once and sends it to every connection.
+func (m *manager) BroadcastJSON(v any) error {
+	b, err := encodeJSON(v)
+	if err != nil {
+		return err
+	}
+	return m.BroadcastE(httpws.TextMessage, b).Err()
+}
@@ -0,0 +1,86 @@
+package websocket
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	httpws "github.com/gorilla/websocket"
+)
+
+func TestReapedHandlerLeavesReconnectedPlayerAlone(t *testing.T) {
+	m := NewManager()
+	release := make(chan struct{})
+	var calls atomic.Int32
+	h := NewHandler(m, quietLogger(), WithHandlerFunc(func(ctx context.Context, conn *SafeConn) {
+		if calls.Add(1) == 1 {
+			<-release // keep the first handler running past its reaping
+			return
+		}
+		defaultEcho(ctx, conn)
+	}))
+	url := serve(t, h) + "?pid=p1"
+	ctx := context.Background()
+
+	dial(t, url)
+	waitFor(t, "the first connection", func() bool { return m.ActiveCount(ctx) == 1 })
+	if n := m.ReapStale(ctx, 0); n != 1 {
+		t.Fatalf("ReapStale reaped %d connections, want 1", n)
+	}
+
+	second, _ := dial(t, url)
+	waitFor(t, "the reconnection", func() bool { return calls.Load() == 2 })
+	close(release)
+
+	// Give the first handler's deferred cleanup time to run.
+	time.Sleep(50 * time.Millisecond)
+	if n := m.ActiveCount(ctx); n != 1 {
+		t.Fatalf("ActiveCount = %d after the old handler exited, want 1", n)
+	}
+	if err := m.SendTo("p1", httpws.TextMessage, []byte("still here")); err != nil {
+		t.Fatalf("SendTo the reconnected player: %v", err)
+	}
+	_ = second.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, msg, err := second.ReadMessage(); err != nil || string(msg) != "still here" {
+		t.Fatalf("read = %q, %v; want the message on the new connection", msg, err)
+	}
+}
+
+func TestAsyncManagerWritesAndHandlerWritesShareOneConn(t *testing.T) {
+	m := NewManager(WithAsyncWrites(64))
+	h := NewHandler(m, quietLogger())
+	c, _ := dial(t, serve(t, h)+"?pid=p1")
+	ctx := context.Background()
+	waitFor(t, "the connection", func() bool { return m.ActiveCount(ctx) == 1 })
+
+	const n = 200
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			for m.SendTo("p1", httpws.TextMessage, []byte("push")) != nil {
+				time.Sleep(time.Millisecond) // queue full
+			}
+		}
+	}()
+	for i := 0; i < n; i++ {
+		if err := c.WriteMessage(httpws.TextMessage, []byte("echo")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+
+	counts := map[string]int{}
+	_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for counts["push"]+counts["echo"] < 2*n {
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			t.Fatalf("read after %v: %v", counts, err)
+		}
+		counts[string(msg)]++
+	}
+	if counts["push"] != n || counts["echo"] != n {
+		t.Fatalf("got %v, want %d of each", counts, n)
+	}
+}
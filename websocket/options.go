@@ -4,6 +4,7 @@ import (
 	"time"
 
 	httpws "github.com/gorilla/websocket"
+	"github.com/shadowofcards/go-toolkit/lifecycle"
 	"github.com/shadowofcards/go-toolkit/logging"
 	"github.com/shadowofcards/go-toolkit/metrics"
 )
@@ -19,7 +20,28 @@ func WithPingPong(pw, pp time.Duration) Option {
 func WithHeartbeatPublisher(p HeartbeatPublisher) Option {
 	return func(h *Handler) { h.heartbeatPublisher = p }
 }
+
+// WithAllowedOrigins restricts upgrades to the given origins. Entries may be
+// "*" or use a subdomain wildcard such as "https://*.example.com".
 func WithAllowedOrigins(origins ...string) Option {
 	return func(h *Handler) { h.allowedOrigins = origins }
 }
 func WithMetrics(rc metrics.Recorder) Option { return func(h *Handler) { h.metrics = rc } }
+
+// WithReauthenticator enables SafeConn.Reauthenticate so long-lived connections
+// can refresh their token without reconnecting.
+func WithReauthenticator(fn Reauthenticator) Option { return func(h *Handler) { h.reauth = fn } }
+
+// WithReadiness rejects new upgrades with 503 DRAINING once r is no longer
+// ready; established connections are left to drain.
+func WithReadiness(r *lifecycle.Readiness) Option { return func(h *Handler) { h.readiness = r } }
+
+// WithSubprotocols sets the subprotocols the server supports, in preference
+// order; the negotiated one is available from SafeConn.Subprotocol().
+func WithSubprotocols(protocols ...string) Option {
+	return func(h *Handler) { h.upgrader.Subprotocols = protocols }
+}
+
+// WithCloseGrace sets how long the close frame write may take when the server
+// ends a connection (default 1s).
+func WithCloseGrace(d time.Duration) Option { return func(h *Handler) { h.closeGrace = d } }
@@ -0,0 +1,66 @@
+package websocket
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WithOriginValidator replaces the origin check entirely; fn receives the raw
+// Origin header. WithAllowedOrigins and WithEmptyOrigin are ignored.
+func WithOriginValidator(fn func(origin string) bool) Option {
+	return func(h *Handler) { h.originValidator = fn }
+}
+
+// WithEmptyOrigin sets whether requests without an Origin header (native
+// clients) are accepted. By default they are accepted unless
+// WithAllowedOrigins is set.
+func WithEmptyOrigin(allow bool) Option {
+	return func(h *Handler) { h.emptyOrigin = &allow }
+}
+
+func (h *Handler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if h.originValidator != nil {
+		return h.originValidator(origin)
+	}
+	if origin == "" {
+		if h.emptyOrigin != nil {
+			return *h.emptyOrigin
+		}
+		return len(h.allowedOrigins) == 0
+	}
+	if len(h.allowedOrigins) > 0 {
+		for _, a := range h.allowedOrigins {
+			if matchOrigin(a, origin) {
+				return true
+			}
+		}
+		return false
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return u.Host == r.Host
+}
+
+// matchOrigin reports whether origin matches pattern. A pattern is an exact
+// origin, "*", or an origin whose host starts with "*." to match any
+// subdomain (not the apex), e.g. "https://*.example.com".
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" || strings.EqualFold(pattern, origin) {
+		return true
+	}
+	scheme, host, ok := strings.Cut(pattern, "://*.")
+	if !ok {
+		return false
+	}
+	u, err := url.Parse(origin)
+	if err != nil || !strings.EqualFold(u.Scheme, scheme) {
+		return false
+	}
+	suffix := "." + strings.ToLower(host)
+	got := strings.ToLower(u.Host)
+	return strings.HasSuffix(got, suffix) && len(got) > len(suffix)
+}
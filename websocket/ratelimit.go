@@ -0,0 +1,85 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+
+	httpws "github.com/gorilla/websocket"
+	apperr "github.com/shadowofcards/go-toolkit/errors"
+	"golang.org/x/time/rate"
+)
+
+var ErrRateLimited = apperr.Define("RATE_LIMITED", http.StatusTooManyRequests, "message rate limit exceeded")
+
+type msgLimit struct {
+	perSecond int
+	burst     int
+	warnings  int
+}
+
+// connLimiter is the per-connection token bucket applied by SafeConn.ReadMessage.
+type connLimiter struct {
+	*rate.Limiter
+	warningsLeft int
+	onLimited    func()
+}
+
+// WithMessageRateLimit caps every connection at perSecond messages with the
+// given burst. Exceeding it closes the connection with 1008 (policy violation)
+// and increments ws_rate_limited_total.
+func WithMessageRateLimit(perSecond, burst int) Option {
+	return func(h *Handler) {
+		if burst < 1 {
+			burst = 1
+		}
+		if h.msgLimit == nil {
+			h.msgLimit = &msgLimit{}
+		}
+		h.msgLimit.perSecond, h.msgLimit.burst = perSecond, burst
+	}
+}
+
+// WithRateLimitWarnings lets a connection exceed its message rate n times,
+// each time dropping the message and sending a RATE_LIMITED error frame,
+// before it is closed. Requires WithMessageRateLimit.
+func WithRateLimitWarnings(n int) Option {
+	return func(h *Handler) {
+		if h.msgLimit == nil {
+			h.msgLimit = &msgLimit{}
+		}
+		h.msgLimit.warnings = n
+	}
+}
+
+func (l *msgLimit) newLimiter(onLimited func()) *connLimiter {
+	if l == nil || l.perSecond <= 0 {
+		return nil
+	}
+	return &connLimiter{
+		Limiter:      rate.NewLimiter(rate.Limit(l.perSecond), l.burst),
+		warningsLeft: l.warnings,
+		onLimited:    onLimited,
+	}
+}
+
+// ReadMessage reads the next message, enforcing the handler's message rate
+// limit when one is configured.
+func (c *SafeConn) ReadMessage() (int, []byte, error) {
+	for {
+		mt, msg, err := c.Conn.ReadMessage()
+		if err != nil || c.limiter == nil || c.limiter.Allow() {
+			return mt, msg, err
+		}
+		if c.limiter.onLimited != nil {
+			c.limiter.onLimited()
+		}
+		if c.limiter.warningsLeft <= 0 {
+			_ = c.CloseWithCode(httpws.ClosePolicyViolation, "rate limit exceeded")
+			return 0, nil, ErrRateLimited
+		}
+		c.limiter.warningsLeft--
+		if b, err := json.Marshal(map[string]any{"type": "error", "payload": ErrRateLimited}); err == nil {
+			_ = c.WriteMessage(httpws.TextMessage, b)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	httpws "github.com/gorilla/websocket"
+	apperr "github.com/shadowofcards/go-toolkit/errors"
+)
+
+/*──────────────────────────────
+   ROUTER
+──────────────────────────────*/
+
+// Envelope is the wire format dispatched by Router.
+type Envelope struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// RouteFunc handles the payload of one message type.
+type RouteFunc func(ctx context.Context, conn *SafeConn, payload json.RawMessage) error
+
+// FallbackFunc handles messages whose type has no registered route.
+type FallbackFunc func(ctx context.Context, conn *SafeConn, env Envelope) error
+
+// RouteErrorFunc is called when a message is malformed or its route fails.
+type RouteErrorFunc func(ctx context.Context, conn *SafeConn, env Envelope, err error)
+
+var (
+	ErrInvalidMessage = apperr.Define("INVALID_MESSAGE", http.StatusBadRequest, "message is not a valid envelope")
+
+	ErrUnknownMessageType = apperr.Define("UNKNOWN_MESSAGE_TYPE", http.StatusNotFound, "no handler for message type")
+)
+
+// Router dispatches incoming {"type":...,"payload":...} messages to the route
+// registered for their type. Use it with WithHandlerFunc(r.HandlerFunc()).
+type Router struct {
+	mu       sync.RWMutex
+	routes   map[string]RouteFunc
+	fallback FallbackFunc
+	onError  RouteErrorFunc
+}
+
+// NewRouter returns a Router that answers unknown types with
+// UNKNOWN_MESSAGE_TYPE and reports errors to the client as
+// {"type":"error","payload":{"code":...,"message":...}}.
+func NewRouter() *Router {
+	return &Router{
+		routes:   map[string]RouteFunc{},
+		fallback: unknownType,
+		onError:  sendError,
+	}
+}
+
+// On registers fn for msgType, replacing any previous route.
+func (r *Router) On(msgType string, fn RouteFunc) *Router {
+	r.mu.Lock()
+	r.routes[msgType] = fn
+	r.mu.Unlock()
+	return r
+}
+
+// Fallback sets the handler for types without a route.
+func (r *Router) Fallback(fn FallbackFunc) *Router {
+	r.mu.Lock()
+	r.fallback = fn
+	r.mu.Unlock()
+	return r
+}
+
+// OnError sets how malformed messages and route errors are handled.
+func (r *Router) OnError(fn RouteErrorFunc) *Router {
+	r.mu.Lock()
+	r.onError = fn
+	r.mu.Unlock()
+	return r
+}
+
+// HandlerFunc returns the read loop: it reads until the connection fails and
+// dispatches every message with the connection context, so identity refreshed
+// by Reauthenticate is visible to routes.
+func (r *Router) HandlerFunc() HandlerFunc {
+	return func(_ context.Context, conn *SafeConn) {
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			r.Dispatch(conn.Context(), conn, msg)
+		}
+	}
+}
+
+// Dispatch decodes msg and runs the matching route, the fallback, or the
+// error handler.
+func (r *Router) Dispatch(ctx context.Context, conn *SafeConn, msg []byte) {
+	r.mu.RLock()
+	fallback, onError := r.fallback, r.onError
+	r.mu.RUnlock()
+
+	var env Envelope
+	if err := json.Unmarshal(msg, &env); err != nil || env.Type == "" {
+		if onError != nil {
+			onError(ctx, conn, env, ErrInvalidMessage.WithError(err))
+		}
+		return
+	}
+
+	r.mu.RLock()
+	fn, ok := r.routes[env.Type]
+	r.mu.RUnlock()
+
+	var err error
+	switch {
+	case ok:
+		err = fn(ctx, conn, env.Payload)
+	case fallback != nil:
+		err = fallback(ctx, conn, env)
+	}
+	if err != nil && onError != nil {
+		onError(ctx, conn, env, err)
+	}
+}
+
+func unknownType(_ context.Context, _ *SafeConn, env Envelope) error {
+	return ErrUnknownMessageType.WithContext("type", env.Type)
+}
+
+func sendError(_ context.Context, conn *SafeConn, env Envelope, err error) {
+	ae, ok := apperr.FromError(err)
+	if !ok {
+		ae = errInternal
+	}
+	b, merr := json.Marshal(map[string]any{"type": "error", "ref": env.Type, "payload": ae})
+	if merr != nil {
+		return
+	}
+	_ = conn.WriteMessage(httpws.TextMessage, b)
+}
@@ -1,25 +1,142 @@
 package websocket
 
 import (
+	"context"
+	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	httpws "github.com/gorilla/websocket"
+	apperr "github.com/shadowofcards/go-toolkit/errors"
 )
 
+// Reauthenticator validates a token received over an open connection and returns
+// the context carrying the refreshed identity.
+type Reauthenticator func(ctx context.Context, token string) (context.Context, error)
+
+// ctxKeyConnCancel carries the CancelFunc of a connection context from the
+// Handler to Manager.Disconnect.
+type ctxKeyConnCancel struct{}
+
+var ErrReauthUnsupported = apperr.Define("REAUTH_UNSUPPORTED", http.StatusNotImplemented, "reauthentication is not configured")
+
 type SafeConn struct {
 	*httpws.Conn
 	mu sync.Mutex
+
+	ctxMu  sync.RWMutex
+	ctx    context.Context
+	reauth Reauthenticator
+
+	dead     atomic.Bool
+	queue    *writeQueue
+	lastSeen atomic.Int64
+
+	closeGrace time.Duration
+	closing    atomic.Bool
+
+	limiter *connLimiter
+
+	attrMu sync.RWMutex
+	attrs  map[string]any
 }
 
+// defaultCloseGrace bounds the close frame write when no grace is configured.
+const defaultCloseGrace = time.Second
+
 func (c *SafeConn) WriteMessage(mt int, data []byte) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	return c.Conn.WriteMessage(mt, data)
 }
 
+// WriteMessageDeadline writes a message that fails once deadline passes. A zero
+// deadline means no limit.
+func (c *SafeConn) WriteMessageDeadline(mt int, data []byte, deadline time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if deadline.IsZero() {
+		return c.Conn.WriteMessage(mt, data)
+	}
+	if err := c.Conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	defer c.Conn.SetWriteDeadline(time.Time{})
+	return c.Conn.WriteMessage(mt, data)
+}
+
 func (c *SafeConn) WriteControl(mt int, data []byte, deadline time.Time) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	return c.Conn.WriteControl(mt, data, deadline)
 }
+
+// Context returns the connection context, including any identity refreshed by
+// Reauthenticate.
+func (c *SafeConn) Context() context.Context {
+	c.ctxMu.RLock()
+	defer c.ctxMu.RUnlock()
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// Set stores a per-connection attribute such as validated claims or the
+// connection's subscriptions.
+func (c *SafeConn) Set(key string, value any) {
+	c.attrMu.Lock()
+	defer c.attrMu.Unlock()
+	if c.attrs == nil {
+		c.attrs = map[string]any{}
+	}
+	c.attrs[key] = value
+}
+
+// Get returns the attribute stored under key.
+func (c *SafeConn) Get(key string) (any, bool) {
+	c.attrMu.RLock()
+	defer c.attrMu.RUnlock()
+	v, ok := c.attrs[key]
+	return v, ok
+}
+
+// Reauthenticate validates a token sent mid-connection (typically in a dedicated
+// frame read by the HandlerFunc) and, on success, replaces the connection context
+// with the refreshed identity.
+func (c *SafeConn) Reauthenticate(token string) error {
+	if c.reauth == nil {
+		return ErrReauthUnsupported
+	}
+	ctx, err := c.reauth(c.Context(), token)
+	if err != nil {
+		return err
+	}
+	c.ctxMu.Lock()
+	c.ctx = ctx
+	c.ctxMu.Unlock()
+	return nil
+}
+
+// CloseWithCode sends a close frame with code and reason (waiting at most the
+// handler's close grace for the write) and then closes the connection. Only the
+// first call has an effect. Close, promoted from the gorilla connection, still
+// closes without a close frame.
+func (c *SafeConn) CloseWithCode(code int, reason string) error {
+	if !c.closing.CompareAndSwap(false, true) {
+		return nil
+	}
+	grace := c.closeGrace
+	if grace <= 0 {
+		grace = defaultCloseGrace
+	}
+	werr := c.WriteControl(httpws.CloseMessage, httpws.FormatCloseMessage(code, reason), time.Now().Add(grace))
+	if err := c.Conn.Close(); err != nil {
+		return err
+	}
+	if werr == httpws.ErrCloseSent {
+		return nil
+	}
+	return werr
+}
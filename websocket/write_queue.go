@@ -0,0 +1,75 @@
+package websocket
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	apperr "github.com/shadowofcards/go-toolkit/errors"
+)
+
+var ErrSlowConsumer = apperr.Define("SLOW_CONSUMER", http.StatusServiceUnavailable, "connection write buffer is full")
+
+type queuedWrite struct {
+	mt   int
+	data []byte
+}
+
+// writeQueue feeds a SafeConn from a dedicated goroutine so one slow client
+// does not hold up senders iterating over many connections.
+type writeQueue struct {
+	mu     sync.RWMutex
+	closed bool
+	ch     chan queuedWrite
+	done   chan struct{}
+}
+
+// startWriter attaches a queue of size buf to c. deadline is evaluated per
+// write; onErr is called for every failed write.
+func (c *SafeConn) startWriter(buf int, deadline func() time.Time, onErr func(error)) {
+	q := &writeQueue{ch: make(chan queuedWrite, buf), done: make(chan struct{})}
+	c.queue = q
+	go func() {
+		defer close(q.done)
+		for w := range q.ch {
+			if q.isClosed() {
+				continue
+			}
+			if err := c.WriteMessageDeadline(w.mt, w.data, deadline()); err != nil && onErr != nil {
+				onErr(err)
+			}
+		}
+	}()
+}
+
+// enqueue queues a write without blocking.
+func (q *writeQueue) enqueue(mt int, data []byte) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrSlowConsumer.WithMessage("connection is closing")
+	}
+	select {
+	case q.ch <- queuedWrite{mt: mt, data: data}:
+		return nil
+	default:
+		return ErrSlowConsumer
+	}
+}
+
+func (q *writeQueue) isClosed() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.closed
+}
+
+// stop closes the queue; pending writes are discarded and the writer goroutine
+// exits.
+func (q *writeQueue) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.ch)
+	}
+}